@@ -0,0 +1,66 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// RedisLogger routes the messages of github.com/redis/go-redis, which
+// only reports problems such as dropped connections, to the package at
+// notice level:
+//
+//	redis.SetLogger(log.RedisLogger{})
+type RedisLogger struct{}
+
+// Printf implements go-redis's internal.Logging.
+func (RedisLogger) Printf(ctx context.Context, format string, v ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	bind(phuslog.WarnLevel).Context(Ctx(ctx)).
+		Str("component", "redis").
+		Msgf(format, v...)
+}
+
+// EntLog logs the statements entgo.io/ent prints in debug mode at debug
+// level:
+//
+//	client := ent.NewClient(ent.Driver(drv), ent.Log(log.EntLog), ent.Debug())
+func EntLog(v ...any) {
+	bind(phuslog.DebugLevel).
+		Str("component", "ent").
+		Msg(fmt.Sprint(v...))
+}
+
+// Query logs a database statement that started at begin and affected rows
+// rows, at debug level, or with err at the level its classifier assigns.
+// It serves ORM logger shims, such as the Trace method of a
+// gorm.io/gorm/logger.Interface:
+//
+//	func (gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+//		sql, rows := fc()
+//		log.Query(ctx, begin, sql, rows, err)
+//	}
+func Query(ctx context.Context, begin time.Time, statement string, rows int64, err error) {
+	level := phuslog.DebugLevel
+	class := ""
+	if err != nil {
+		class, level = classify(err)
+	}
+	e := bind(level).Context(Ctx(ctx)).
+		Str("db.statement", statement).
+		Int64("db.rows", rows).
+		Dur("db.duration", time.Since(begin))
+	if err == nil {
+		e.Msg("query")
+		return
+	}
+	if class != "" {
+		e.Str("error.class", class)
+	}
+	addErr(e, err)
+	e.Msg("query failed")
+}
@@ -0,0 +1,194 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// timeNow is replaced in tests.
+var timeNow = time.Now
+
+// ArchiveWriter writes records as NDJSON into time-partitioned files under
+// Dir, named Dir/2006/01/02.ndjson, or Dir/2006/01/02/15.ndjson when
+// Hourly, in UTC. It serves as a cheap long-term archive, independent of
+// the configured output:
+//
+//	log.SetOutputs(
+//		log.Output{Writer: log.NewConsoleWriter(os.Stderr)},
+//		log.Output{Writer: &log.ArchiveWriter{Dir: "/var/log/app", Compress: true}},
+//	)
+type ArchiveWriter struct {
+	// Dir is the root of the archive tree.
+	Dir string
+
+	// Hourly partitions files per hour instead of per day.
+	Hourly bool
+
+	// Compress gzips each partition once it is complete, as well as the
+	// current one on Close and any left uncompressed under Dir, as by a
+	// crash, when the first record is written. A partition written again
+	// after it was compressed gets another gzip member appended.
+	Compress bool
+
+	// MaxAge removes partitions last written longer ago than MaxAge.
+	// Partitions are kept forever if zero.
+	MaxAge time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+	name string
+	wg   sync.WaitGroup
+}
+
+// WriteEntry implements phuslog.Writer.
+func (w *ArchiveWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	return w.Write(e.Value())
+}
+
+// Write appends p, a complete record, to the current partition.
+func (w *ArchiveWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	name := w.partition(timeNow().UTC())
+	if name != w.name {
+		first := w.name == ""
+		if err := w.open(name); err != nil {
+			return 0, err
+		}
+		if first && w.Compress {
+			w.wg.Add(1)
+			go func() {
+				defer w.wg.Done()
+				w.compressAll(name)
+			}()
+		}
+	}
+	return w.file.Write(p)
+}
+
+// Close closes the current partition, waits for pending compression and
+// retention work, and compresses the partitions left uncompressed.
+func (w *ArchiveWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		err = w.file.Close()
+		w.file, w.name = nil, ""
+	}
+	w.wg.Wait()
+	if w.Compress {
+		w.compressAll("")
+	}
+	return
+}
+
+func (w *ArchiveWriter) partition(now time.Time) string {
+	if w.Hourly {
+		return filepath.Join(w.Dir, now.Format("2006/01/02/15.ndjson"))
+	}
+	return filepath.Join(w.Dir, now.Format("2006/01/02.ndjson"))
+}
+
+// open switches to partition name, handing the previous one to finish.
+func (w *ArchiveWriter) open(name string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	prev := w.file
+	w.file, w.name = f, name
+	if prev != nil {
+		w.wg.Add(1)
+		go w.finish(prev)
+	}
+	return nil
+}
+
+// finish closes a complete partition, compresses it and applies MaxAge.
+func (w *ArchiveWriter) finish(f *os.File) {
+	defer w.wg.Done()
+	f.Close()
+	if w.Compress {
+		if err := gzipFile(f.Name()); err != nil {
+			selfLog(phuslog.ErrorLevel, "archive compression failed", err)
+		}
+	}
+	if w.MaxAge > 0 {
+		w.expire(time.Now().Add(-w.MaxAge))
+	}
+}
+
+// compressAll compresses the partitions under Dir other than current.
+func (w *ArchiveWriter) compressAll(current string) {
+	filepath.WalkDir(w.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".ndjson") || path == current {
+			return nil
+		}
+		if err := gzipFile(path); err != nil {
+			selfLog(phuslog.ErrorLevel, "archive compression failed", err)
+		}
+		return nil
+	})
+}
+
+// expire removes the partitions last modified before cutoff.
+func (w *ArchiveWriter) expire(cutoff time.Time) {
+	filepath.WalkDir(w.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if !strings.HasSuffix(path, ".ndjson") && !strings.HasSuffix(path, ".ndjson.gz") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			if err := os.Remove(path); err != nil {
+				selfLog(phuslog.WarnLevel, "archive retention failed", err)
+			}
+		}
+		return nil
+	})
+}
+
+// gzipFile moves name into name.gz, appending a gzip member if it exists.
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := dst.Stat()
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		dst.Truncate(info.Size())
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+var (
+	// _batchMu is held for the duration of a Batch, and read-locked by
+	// records written from other goroutines.
+	_batchMu sync.RWMutex
+	// _batchOwner is the id of the goroutine running a Batch, or 0.
+	_batchOwner atomic.Int64
+	// _batching is set once Batch is first used, so that programs that
+	// never batch skip the lock.
+	_batching atomic.Bool
+)
+
+// Batch runs fn and returns the batch ID given to the records it logs from
+// the current goroutine, in a batch_id field. Those records are written
+// contiguously: records logged meanwhile by other goroutines wait until fn
+// returns. This keeps multi-record reports, such as a configuration dump at
+// startup, together in console output.
+//
+// fn must not wait for other goroutines that log. A Batch within a Batch
+// joins the outer one.
+func Batch(fn func()) (id string) {
+	goid := phuslog.Goid()
+	if _batchOwner.Load() == goid {
+		fn()
+		return ""
+	}
+	id = phuslog.NewXID().String()
+	_batching.Store(true)
+	_batchMu.Lock()
+	_batchOwner.Store(goid)
+	unbind := Bind(func(e *phuslog.Entry) { e.Str("batch_id", id) })
+	defer func() {
+		unbind()
+		_batchOwner.Store(0)
+		_batchMu.Unlock()
+	}()
+	fn()
+	return id
+}
+
+// waitBatch blocks until a Batch running on another goroutine is done, and
+// keeps another from starting until the returned function is called, once
+// the record is written.
+func waitBatch() (done func()) {
+	if !_batching.Load() || _batchOwner.Load() == phuslog.Goid() {
+		return batchNone
+	}
+	_batchMu.RLock()
+	return batchDone
+}
+
+func batchNone() {}
+
+func batchDone() { _batchMu.RUnlock() }
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"os"
@@ -53,7 +55,13 @@ func BenchmarkPatchedSlog(b *testing.B) {
 }
 
 func TestMain(m *testing.M) {
+	if os.Getenv("LOG_TEST_FIRST_RECORD") != "" {
+		// Run by TestLazyInit: log before anything set the package up.
+		Retry(context.Background(), "connect", 1, errors.New("refused"), time.Second, 0)
+		os.Exit(0)
+	}
 	_defaultOutput = io.Discard
+	Init()
 	os.Exit(m.Run())
 }
 
@@ -68,3 +76,58 @@ func BenchmarkCaller(b *testing.B) {
 		Notice().Int("a", 3).Int("b", 4).Msg("hello world james")
 	}
 }
+
+// Allocation targets per record, as published in the package doc.
+// Exceeding them is a regression.
+const (
+	jsonAllocsTarget    = 0
+	slogAllocsTarget    = 0
+	consoleAllocsTarget = 8
+)
+
+func BenchmarkJSON(b *testing.B) {
+	SetWriter(io.Discard)
+	b.ReportAllocs()
+	for b.Loop() {
+		Info().Int("a", 3).Str("b", "x").Msg("hello world james")
+	}
+}
+
+func BenchmarkSlogDefault(b *testing.B) {
+	SetWriter(io.Discard)
+	b.ReportAllocs()
+	for b.Loop() {
+		slog.Info("hello world james", "a", 3, "b", "x")
+	}
+}
+
+func BenchmarkConsole(b *testing.B) {
+	setWriter(NewConsoleWriter(io.Discard))
+	defer SetWriter(io.Discard)
+	b.ReportAllocs()
+	for b.Loop() {
+		Info().Int("a", 3).Str("b", "x").Msg("hello world james")
+	}
+}
+
+func TestAllocsTargets(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector inflates allocations")
+	}
+	defer SetWriter(io.Discard)
+	for _, tt := range []struct {
+		name   string
+		writer phuslog.Writer
+		log    func()
+		target float64
+	}{
+		{"json", phuslog.IOWriter{Writer: io.Discard}, func() { Info().Int("a", 3).Str("b", "x").Msg("hello") }, jsonAllocsTarget},
+		{"slog", phuslog.IOWriter{Writer: io.Discard}, func() { slog.Info("hello", "a", 3, "b", "x") }, slogAllocsTarget},
+		{"console", NewConsoleWriter(io.Discard), func() { Info().Int("a", 3).Str("b", "x").Msg("hello") }, consoleAllocsTarget},
+	} {
+		setWriter(tt.writer)
+		if n := testing.AllocsPerRun(100, tt.log); n > tt.target {
+			t.Errorf("%s: %v allocs per record, target %v", tt.name, n, tt.target)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	phuslog "github.com/phuslu/log"
+)
+
+// Builder accumulates fields, some of them conditionally, to be sent with
+// a single entry:
+//
+//	var b log.Builder
+//	b.Add(log.Any("user", id))
+//	b.AddIf(err != nil, log.Any("error", err))
+//	log.Info().Func(b.Fields).Msg("login")
+type Builder struct {
+	fields []func(e *phuslog.Entry)
+}
+
+// Any returns a field adding value under key.
+func Any(key string, value any) func(e *phuslog.Entry) {
+	return func(e *phuslog.Entry) { e.Any(key, value) }
+}
+
+// Add appends fields to b.
+func (b *Builder) Add(fields ...func(e *phuslog.Entry)) *Builder {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// AddIf appends fields to b if cond is true.
+func (b *Builder) AddIf(cond bool, fields ...func(e *phuslog.Entry)) *Builder {
+	if cond {
+		b.fields = append(b.fields, fields...)
+	}
+	return b
+}
+
+// Fields adds the accumulated fields to e, for use with Entry.Func.
+func (b *Builder) Fields(e *phuslog.Entry) {
+	for _, f := range b.fields {
+		f(e)
+	}
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// _catalog holds the translations set by SetCatalog.
+var _catalog atomic.Pointer[map[string]string]
+
+// SetCatalog localizes messages logged with Msgk and formatted with T:
+// catalog maps an English format string to its translation, which takes
+// the same arguments. Messages missing from it stay in English, as do all
+// messages if catalog is nil.
+//
+//	log.SetCatalog(map[string]string{"loaded %d plugins": "%d Plugins geladen"})
+func SetCatalog(catalog map[string]string) {
+	if catalog == nil {
+		_catalog.Store(nil)
+		return
+	}
+	_catalog.Store(&catalog)
+}
+
+// translate returns the translation of format, or format if there is none.
+func translate(format string) (string, bool) {
+	if c := _catalog.Load(); c != nil {
+		if t, ok := (*c)[format]; ok {
+			return t, true
+		}
+	}
+	return format, false
+}
+
+// T returns format translated by the catalog and formatted with args.
+func T(format string, args ...any) string {
+	t, _ := translate(format)
+	return fmt.Sprintf(t, args...)
+}
+
+// Msgk sends e with format translated by the catalog and formatted with
+// args. A translated record also carries the English message in msg_en,
+// so it can be searched for regardless of language.
+//
+//	log.Msgk(log.Info(), "loaded %d plugins", n)
+func Msgk(e *phuslog.Entry, format string, args ...any) {
+	if e == nil {
+		return
+	}
+	t, ok := translate(format)
+	if ok {
+		e.Str("msg_en", fmt.Sprintf(format, args...))
+	}
+	e.Msgf(t, args...)
+}
@@ -0,0 +1,41 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+var (
+	_codes sync.Map
+	// _checkCodes is set once codes are registered.
+	_checkCodes atomic.Bool
+)
+
+// RegisterCodes declares the error codes Code accepts. Until codes are
+// registered, any code is accepted.
+func RegisterCodes(codes ...string) {
+	for _, c := range codes {
+		_codes.Store(c, struct{}{})
+	}
+	_checkCodes.Store(true)
+}
+
+// Code returns a field adding the stable error code code in error.code,
+// for alerting rules that should not depend on message text:
+//
+//	log.Error().Func(log.Code("AUTH_401")).Msg("token rejected")
+//
+// A code not registered with RegisterCodes is reported in a "!BADCODE"
+// field as well.
+func Code(code string) func(e *phuslog.Entry) {
+	return func(e *phuslog.Entry) {
+		e.Str("error.code", code)
+		if _checkCodes.Load() {
+			if _, ok := _codes.Load(code); !ok {
+				e.Str("!BADCODE", "unregistered code "+code)
+			}
+		}
+	}
+}
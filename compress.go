@@ -0,0 +1,87 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// A Compressor is a streaming compressor, such as a *zstd.Encoder from
+// github.com/klauspost/compress/zstd or a *gzip.Writer.
+type Compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// CompressedWriter writes records through a streaming compressor, for
+// verbose trace captures that would fill the disk as plain text:
+//
+//	enc, _ := zstd.NewWriter(f)
+//	log.SetOutputs(log.Output{Writer: &log.CompressedWriter{Compressor: enc}})
+//
+// The compressor is flushed within FlushInterval of each write, so that
+// the output up to then can be decompressed should the process die.
+type CompressedWriter struct {
+	Compressor Compressor
+
+	// FlushInterval is how long records may stay buffered in the
+	// compressor. One second if zero.
+	FlushInterval time.Duration
+
+	mu      sync.Mutex
+	pending *time.Timer
+	closed  bool
+}
+
+// WriteEntry implements phuslog.Writer.
+func (w *CompressedWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	return w.Write(e.Value())
+}
+
+// Write compresses p, a complete record.
+func (w *CompressedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	n, err := w.Compressor.Write(p)
+	if w.pending == nil {
+		every := w.FlushInterval
+		if every <= 0 {
+			every = time.Second
+		}
+		w.pending = time.AfterFunc(every, w.flush)
+	}
+	return n, err
+}
+
+func (w *CompressedWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = nil
+	if w.closed {
+		return
+	}
+	if err := w.Compressor.Flush(); err != nil {
+		selfLog(phuslog.ErrorLevel, "compressed output flush failed", err)
+	}
+}
+
+// Close ends the compressed stream. The writer underneath the compressor
+// is left open.
+func (w *CompressedWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.pending != nil {
+		w.pending.Stop()
+		w.pending = nil
+	}
+	return w.Compressor.Close()
+}
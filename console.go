@@ -0,0 +1,135 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// ConsoleOptions configure NewConsoleHandler.
+type ConsoleOptions struct {
+	// Level is the minimum level written. All levels are written if nil.
+	Level slog.Leveler
+
+	// Color switches from logfmt lines to phuslog's colorized layout.
+	Color bool
+}
+
+// NewConsoleHandler returns a slog.Handler writing human-readable lines to
+// w, the same way the default console output does.
+func NewConsoleHandler(w io.Writer, opts *ConsoleOptions) slog.Handler {
+	if opts == nil {
+		opts = &ConsoleOptions{}
+	}
+	lazyInit()
+	l := phuslog.Logger{
+		TimeFormat: _default.TimeFormat,
+		Writer:     newConsoleWriter(w, opts.Color),
+		Level:      phuslog.TraceLevel,
+		Context:    resource(),
+	}
+	h := l.Slog().Handler()
+	if opts.Level != nil {
+		h = &levelHandler{h, opts.Level}
+	}
+	return h
+}
+
+// NewConsoleWriter returns a writer rendering records to w as the default
+// console output does, for use with SetOutputs.
+func NewConsoleWriter(w io.Writer) phuslog.Writer {
+	return newConsoleWriter(w, false)
+}
+
+func newConsoleWriter(w io.Writer, color bool) *phuslog.ConsoleWriter {
+	if color {
+		return &phuslog.ConsoleWriter{
+			ColorOutput:    true,
+			QuoteString:    true,
+			EndWithMessage: true,
+			Writer:         w,
+		}
+	}
+	return &phuslog.ConsoleWriter{
+		Formatter: formatConsole,
+		Writer:    w,
+	}
+}
+
+// formatConsole renders a record as a logfmt line, moving multi-line string
+// values such as dumps, Hex and Diff fields below it as indented blocks.
+// Records logged within a Scope are indented after the level instead of
+// showing their log.scope field.
+func formatConsole(out io.Writer, args *phuslog.FormatterArgs) (int, error) {
+	logfmt := phuslog.LogfmtFormatter{TimeField: "ts"}
+	var blocks [][2]string
+	diff := -1      // the block gathering Diff fields
+	scoped := false // bind writes the scope before any field of the caller
+	kvs := args.KeyValues[:0]
+	for _, kv := range args.KeyValues {
+		if kv.Key == scopeKey && kv.ValueType == 's' && !scoped {
+			args.Level += strings.Repeat("  ", strings.Count(kv.Value, "/")+1)
+			scoped = true
+			continue
+		}
+		if kv.ValueType == 's' && strings.Contains(kv.Value, "\n") {
+			blocks = append(blocks, [2]string{kv.Key, kv.Value})
+			continue
+		}
+		if kv.ValueType == 'o' {
+			if dump, ok := hexBlock(kv.Value); ok {
+				blocks = append(blocks, [2]string{kv.Key, dump})
+				continue
+			}
+			if lines, ok := diffLines(kv.Key, kv.Value, out); ok {
+				if diff < 0 {
+					diff = len(blocks)
+					blocks = append(blocks, [2]string{"diff", ""})
+				}
+				blocks[diff][1] += lines
+				continue
+			}
+		}
+		kvs = append(kvs, kv)
+	}
+	args.KeyValues = kvs
+	if len(blocks) == 0 {
+		return logfmt.Formatter(out, args)
+	}
+
+	var b bytes.Buffer
+	logfmt.Formatter(&b, args)
+	for _, kv := range blocks {
+		b.WriteString("  ")
+		b.WriteString(kv[0])
+		b.WriteString(":\n")
+		for line := range strings.Lines(kv[1]) {
+			b.WriteString("    ")
+			b.WriteString(strings.TrimSuffix(line, "\n"))
+			b.WriteByte('\n')
+		}
+	}
+	return out.Write(b.Bytes())
+}
+
+// levelHandler drops records below level before they reach Handler.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= h.level.Level() && h.Handler.Enabled(ctx, l)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{h.Handler.WithAttrs(attrs), h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{h.Handler.WithGroup(name), h.level}
+}
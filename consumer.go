@@ -0,0 +1,47 @@
+package log
+
+import (
+	"context"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// Message tracks the processing of one message consumed from a queue.
+type Message struct {
+	ctx   context.Context
+	start time.Time
+}
+
+// Consume starts tracking a message read from partition of topic at offset.
+// Records about it, including those logged with Ctx(m.Context()), carry
+// the topic, partition, offset and key.
+func Consume(ctx context.Context, topic string, partition int32, offset int64, key []byte) *Message {
+	ctx = withFields(ctx, func(e *phuslog.Entry) {
+		e.Str("queue.topic", topic).
+			Int32("queue.partition", partition).
+			Int64("queue.offset", offset).
+			Bytes("queue.key", key)
+	})
+	return &Message{ctx: ctx, start: time.Now()}
+}
+
+// Context returns a context carrying the message's fields.
+func (m *Message) Context() context.Context {
+	return m.ctx
+}
+
+// Done logs the processing latency of the message, at debug level on
+// success and at error level with err on failure.
+func (m *Message) Done(err error) {
+	if err == nil {
+		bind(phuslog.DebugLevel).Context(Ctx(m.ctx)).
+			Dur("queue.latency", time.Since(m.start)).
+			Msg("message processed")
+		return
+	}
+	bind(phuslog.ErrorLevel).Caller(2).Context(Ctx(m.ctx)).
+		Dur("queue.latency", time.Since(m.start)).
+		Err(err).
+		Msg("message failed")
+}
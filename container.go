@@ -0,0 +1,52 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// dockerWriter wraps each record in the schema of Docker's json-file log
+// driver, so parsers written for it ingest the output unchanged.
+type dockerWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	stream string
+}
+
+func (w *dockerWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	b, err := json.Marshal(struct {
+		Log    string `json:"log"`
+		Stream string `json:"stream"`
+		Time   string `json:"time"`
+	}{string(e.Value()), w.stream, time.Now().UTC().Format(time.RFC3339Nano)})
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(append(b, '\n'))
+}
+
+// criWriter writes each record in the CRI logging format kubelet uses,
+// "<time> <stream> F <record>", one full line per record.
+type criWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	stream string
+	buf    []byte
+}
+
+func (w *criWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = time.Now().UTC().AppendFormat(w.buf[:0], time.RFC3339Nano)
+	w.buf = append(w.buf, ' ')
+	w.buf = append(w.buf, w.stream...)
+	w.buf = append(w.buf, " F "...)
+	w.buf = append(w.buf, e.Value()...)
+	return w.w.Write(w.buf)
+}
@@ -0,0 +1,97 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// RequestIDHeader is the header WithRequestID reads an incoming request ID
+// from and InjectRequestID writes it to.
+var RequestIDHeader = "X-Request-Id"
+
+type ctxKey int
+
+const (
+	fieldsKey ctxKey = iota
+	requestIDKey
+	tenantKey
+)
+
+// Ctx returns the fields attached to ctx, to be sent with an entry:
+//
+//	log.Info().Context(log.Ctx(ctx)).Msg("handled")
+//
+// Contexts that are done, or whose deadline is less than DeadlineWarning
+// away, also get ctx.deadline_remaining and ctx.err.
+func Ctx(ctx context.Context) phuslog.Context {
+	c := fields(ctx)
+	if _, ok := ctx.Value(tenantKey).(string); !ok {
+		if t := resolveTenant(ctx); t != "" {
+			c = phuslog.NewContext(c[:len(c):len(c)]).Str("tenant", t).Value()
+		}
+	}
+	deadline, ok := ctx.Deadline()
+	if err := ctx.Err(); err != nil || ok && time.Until(deadline) < DeadlineWarning {
+		e := phuslog.NewContext(c[:len(c):len(c)])
+		if ok {
+			e.Dur("ctx.deadline_remaining", time.Until(deadline))
+		}
+		if err != nil {
+			e.Str("ctx.err", err.Error())
+		}
+		c = e.Value()
+	}
+	return c
+}
+
+// DeadlineWarning is how close to its deadline a context must be for Ctx
+// to annotate records with the time remaining.
+var DeadlineWarning = time.Second
+
+// fields returns the fields stored in ctx, without per-record additions.
+func fields(ctx context.Context) phuslog.Context {
+	c, _ := ctx.Value(fieldsKey).(phuslog.Context)
+	return c
+}
+
+// withFields returns a copy of ctx whose fields are extended by f.
+func withFields(ctx context.Context, f func(e *phuslog.Entry)) context.Context {
+	c := fields(ctx)
+	e := phuslog.NewContext(c[:len(c):len(c)])
+	f(e)
+	return context.WithValue(ctx, fieldsKey, e.Value())
+}
+
+// WithRequestID returns a copy of ctx carrying a request ID and the ID itself.
+// The ID is taken from h when it has one under RequestIDHeader, otherwise a
+// new one is generated. The ID is attached to the fields returned by Ctx. A
+// ctx already carrying an ID is returned unchanged, with its ID.
+func WithRequestID(ctx context.Context, h http.Header) (context.Context, string) {
+	if id := RequestID(ctx); id != "" {
+		return ctx, id
+	}
+	id := h.Get(RequestIDHeader)
+	if id == "" {
+		id = phuslog.NewXID().String()
+	}
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	ctx = withFields(ctx, func(e *phuslog.Entry) { e.Str("request_id", id) })
+	return ctx, id
+}
+
+// RequestID returns the request ID stored in ctx by WithRequestID, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// InjectRequestID sets RequestIDHeader on h for outbound requests made on
+// behalf of ctx. It does nothing if ctx carries no request ID.
+func InjectRequestID(ctx context.Context, h http.Header) {
+	if id := RequestID(ctx); id != "" {
+		h.Set(RequestIDHeader, id)
+	}
+}
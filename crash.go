@@ -0,0 +1,153 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// crashBundleEvery is the least time between two crash bundles, so a burst
+// of critical records does not stall the program writing them.
+const crashBundleEvery = time.Minute
+
+var (
+	_crashMu   sync.Mutex
+	_crashDir  string
+	_crashTail [][]byte
+	_crashNext int
+	_crashLast time.Time
+
+	// _crashKeeping is set while records are kept for bundles, and
+	// _crashEnabled while bundles are written at all.
+	_crashKeeping atomic.Bool
+	_crashEnabled atomic.Bool
+
+	// _crashSeq numbers the bundles, and _crashPending holds the bundle
+	// directory of each critical record not written yet.
+	_crashSeq     atomic.Int64
+	_crashPending sync.Map
+)
+
+// SetCrashDir enables crash bundles. A critical record then writes a
+// directory under dir holding the last tail records, up to and including
+// itself, the stacks of all goroutines, the build info and the environment,
+// and carries its path as crash_bundle. At most one bundle is written a
+// minute. Environment values whose names suggest secrets are redacted. An
+// empty dir disables bundles.
+func SetCrashDir(dir string, tail int) {
+	_crashMu.Lock()
+	defer _crashMu.Unlock()
+	_crashDir = dir
+	_crashTail = nil
+	_crashNext = 0
+	_crashLast = time.Time{}
+	if dir != "" && tail > 0 {
+		_crashTail = make([][]byte, tail)
+	}
+	_crashKeeping.Store(len(_crashTail) != 0)
+	_crashEnabled.Store(dir != "")
+}
+
+// keepTail remembers record for the next crash bundle.
+func keepTail(record []byte) {
+	if !_crashKeeping.Load() {
+		return
+	}
+	_crashMu.Lock()
+	defer _crashMu.Unlock()
+	if len(_crashTail) == 0 {
+		return
+	}
+	_crashTail[_crashNext] = append(_crashTail[_crashNext][:0], record...)
+	_crashNext = (_crashNext + 1) % len(_crashTail)
+}
+
+// writeCrashBundle writes the crash bundle reserved for e, if any, once e
+// has been kept in the tail.
+func writeCrashBundle(e *phuslog.Entry) {
+	if !_crashEnabled.Load() {
+		return
+	}
+	dir, ok := _crashPending.LoadAndDelete(e)
+	if !ok {
+		return
+	}
+	_crashMu.Lock()
+	var records []byte
+	for i := range _crashTail {
+		records = append(records, _crashTail[(_crashNext+i)%len(_crashTail)]...)
+	}
+	_crashMu.Unlock()
+	crashBundle(dir.(string), records)
+}
+
+// crashBundle writes a crash bundle with records to dir.
+func crashBundle(dir string, records []byte) {
+	// Bundles hold records and the environment, so only the owner may read them.
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		selfLog(phuslog.ErrorLevel, "crash bundle failed", err)
+		return
+	}
+
+	var build string
+	if info, ok := debug.ReadBuildInfo(); ok {
+		build = info.String()
+	}
+	var environ strings.Builder
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if secretName(k) {
+			v = "REDACTED"
+		}
+		fmt.Fprintf(&environ, "%s=%s\n", k, v)
+	}
+
+	for name, b := range map[string][]byte{
+		"records.ndjson": records,
+		"goroutines.txt": allStacks(),
+		"buildinfo.txt":  []byte(build),
+		"environ.txt":    []byte(environ.String()),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o600); err != nil {
+			selfLog(phuslog.ErrorLevel, "crash bundle failed", err)
+		}
+	}
+}
+
+func secretName(k string) bool {
+	k = strings.ToUpper(k)
+	for _, s := range []string{"SECRET", "TOKEN", "PASSWORD", "PASSWD", "KEY", "CREDENTIAL"} {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// addCrashBundle reserves a crash bundle for e and adds its path, if
+// bundles are enabled and none was written within crashBundleEvery. The
+// bundle is written by the output, after e.
+func addCrashBundle(e *phuslog.Entry) {
+	if !_crashEnabled.Load() {
+		return
+	}
+	_crashMu.Lock()
+	now := time.Now()
+	if _crashDir == "" || !_crashLast.IsZero() && now.Sub(_crashLast) < crashBundleEvery {
+		_crashMu.Unlock()
+		return
+	}
+	_crashLast = now
+	name := fmt.Sprintf("crash-%s-%d-%d", now.UTC().Format("20060102T150405Z"), os.Getpid(), _crashSeq.Add(1))
+	dir := filepath.Join(_crashDir, name)
+	_crashMu.Unlock()
+	_crashPending.Store(e, dir)
+	e.Str("crash_bundle", dir)
+}
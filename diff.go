@@ -0,0 +1,181 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// Diff logs msg at info level with the differences between old and new, and
+// reports whether there were any; nothing is logged otherwise. Both are
+// compared through their JSON encodings, nested objects flattened into
+// dotted keys. Records carry them in diff.added, diff.removed and
+// diff.changed objects, which the console lists below the line as +, - and
+// ~ lines, colorized on a terminal.
+//
+//	log.Diff("config changed", prev, cfg)
+func Diff(msg string, old, new any) bool {
+	a, err := flatten(old)
+	if err == nil {
+		var b map[string]string
+		b, err = flatten(new)
+		if err == nil {
+			return diff(msg, a, b)
+		}
+	}
+	bind(phuslog.ErrorLevel).Caller(2).Str("diff.error", err.Error()).Msg(msg)
+	return false
+}
+
+// diff logs msg with the differences between the flattened a and b.
+func diff(msg string, a, b map[string]string) bool {
+	var added, removed, changed []string
+	for _, k := range slices.Sorted(maps.Keys(b)) {
+		if v, ok := a[k]; !ok {
+			added = append(added, k)
+		} else if v != b[k] {
+			changed = append(changed, k)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(a)) {
+		if _, ok := b[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	if len(added)+len(removed)+len(changed) == 0 {
+		return false
+	}
+	e := bind(phuslog.InfoLevel)
+	if e == nil {
+		return true
+	}
+	if len(added) != 0 {
+		c := phuslog.NewContext(nil)
+		for _, k := range added {
+			c.RawJSONStr(k, b[k])
+		}
+		e.Dict("diff.added", c.Value())
+	}
+	if len(removed) != 0 {
+		c := phuslog.NewContext(nil)
+		for _, k := range removed {
+			c.RawJSONStr(k, a[k])
+		}
+		e.Dict("diff.removed", c.Value())
+	}
+	if len(changed) != 0 {
+		c := phuslog.NewContext(nil)
+		for _, k := range changed {
+			c.Dict(k, phuslog.NewContext(nil).RawJSONStr("old", a[k]).RawJSONStr("new", b[k]).Value())
+		}
+		e.Dict("diff.changed", c.Value())
+	}
+	e.Msg(msg)
+	return true
+}
+
+// diffLines renders value, the JSON form of the diff field key, as the
+// console's +, - or ~ lines for out, reporting whether key was one.
+func diffLines(key, value string, out io.Writer) (string, bool) {
+	var sign, sgr string
+	switch key {
+	case "diff.added":
+		sign, sgr = "+", "32"
+	case "diff.removed":
+		sign, sgr = "-", "31"
+	case "diff.changed":
+		sign, sgr = "~", "33"
+	default:
+		return "", false
+	}
+	fields, err := jsonFields(value)
+	if err != nil {
+		return "", false
+	}
+	f, ok := out.(*os.File)
+	color := ok && phuslog.IsTerminal(f.Fd())
+	var s strings.Builder
+	for _, f := range fields {
+		v := f[1]
+		if sign == "~" {
+			var c struct{ Old, New json.RawMessage }
+			if json.Unmarshal([]byte(v), &c) != nil {
+				return "", false
+			}
+			v = string(c.Old) + " -> " + string(c.New)
+		}
+		if color {
+			s.WriteString("\x1b[" + sgr + "m")
+		}
+		s.WriteString(sign + " " + f[0] + ": " + v)
+		if color {
+			s.WriteString("\x1b[0m")
+		}
+		s.WriteByte('\n')
+	}
+	return s.String(), true
+}
+
+// jsonFields returns the keys and encoded values of the JSON object s, in
+// order.
+func jsonFields(s string) ([][2]string, error) {
+	d := json.NewDecoder(strings.NewReader(s))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return nil, errors.New("log: not a JSON object")
+	}
+	var fields [][2]string
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		var raw json.RawMessage
+		if err := d.Decode(&raw); err != nil {
+			return nil, err
+		}
+		fields = append(fields, [2]string{t.(string), string(raw)})
+	}
+	return fields, nil
+}
+
+// flatten returns the JSON encoding of v as a map from dotted keys to the
+// encoded leaf values. A v that is not an object is a single leaf, "value".
+func flatten(v any) (map[string]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var x any
+	if err := json.Unmarshal(data, &x); err != nil {
+		return nil, err
+	}
+	m := map[string]string{}
+	if _, ok := x.(map[string]any); !ok {
+		m["value"] = string(data)
+		return m, nil
+	}
+	var walk func(prefix string, x any) error
+	walk = func(prefix string, x any) error {
+		if o, ok := x.(map[string]any); ok && (len(o) != 0 || prefix == "") {
+			for k, v := range o {
+				if prefix != "" {
+					k = prefix + "." + k
+				}
+				if err := walk(k, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		b, err := json.Marshal(x)
+		m[prefix] = string(b)
+		return err
+	}
+	return m, walk("", x)
+}
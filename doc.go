@@ -0,0 +1,13 @@
+// Package log is a structured logger on top of phuslu/log that also serves
+// as the slog default.
+//
+// Writing a record allocates at most, per record:
+//
+//	JSON (the default format)   0
+//	slog through the default    0
+//	console                     8
+//
+// The targets are checked by TestAllocsTargets and can be followed in
+// production with ReadStats. BenchmarkJSON, BenchmarkSlogDefault and
+// BenchmarkConsole measure the same paths.
+package log
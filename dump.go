@@ -0,0 +1,169 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// DumpDepth is how deep Dump descends into nested values before eliding
+// them with "...".
+var DumpDepth = 8
+
+// DumpUnexported makes Dump include unexported struct fields.
+var DumpUnexported = false
+
+// Dump logs v at debug level as a pretty-printed, multi-line value under
+// key. Cycles are cut and nesting is limited by DumpDepth.
+func Dump(key string, v any) {
+	if e := bind(phuslog.DebugLevel); e != nil {
+		e.Str(key, Sdump(v)).Msg(key)
+	}
+}
+
+// Sdump returns the text Dump logs for v.
+func Sdump(v any) string {
+	d := dumper{visited: map[uintptr]bool{}}
+	d.dump(reflect.ValueOf(v), 0)
+	return d.b.String()
+}
+
+type dumper struct {
+	b       strings.Builder
+	visited map[uintptr]bool
+}
+
+var (
+	stringerType = reflect.TypeFor[fmt.Stringer]()
+	errorType    = reflect.TypeFor[error]()
+)
+
+func (d *dumper) indent(depth int) {
+	d.b.WriteByte('\n')
+	d.b.WriteString(strings.Repeat("  ", depth))
+}
+
+func (d *dumper) dump(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		d.b.WriteString("nil")
+		return
+	}
+	if v.CanInterface() && (v.Type().Implements(errorType) || v.Type().Implements(stringerType)) {
+		if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+			d.b.WriteString("nil")
+			return
+		}
+		switch x := v.Interface().(type) {
+		case error:
+			d.b.WriteString(strconv.Quote(x.Error()))
+		case fmt.Stringer:
+			d.b.WriteString(strconv.Quote(x.String()))
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			d.b.WriteString("nil")
+			return
+		}
+		if d.visited[v.Pointer()] {
+			fmt.Fprintf(&d.b, "<cycle %s>", v.Type())
+			return
+		}
+		d.visited[v.Pointer()] = true
+		defer delete(d.visited, v.Pointer())
+		d.b.WriteByte('&')
+		d.dump(v.Elem(), depth)
+	case reflect.Interface:
+		d.dump(v.Elem(), depth)
+	case reflect.Struct:
+		d.b.WriteString(v.Type().String())
+		if depth >= DumpDepth {
+			d.b.WriteString("{...}")
+			return
+		}
+		d.b.WriteByte('{')
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() && !DumpUnexported {
+				continue
+			}
+			d.indent(depth + 1)
+			d.b.WriteString(f.Name)
+			d.b.WriteString(": ")
+			d.dump(v.Field(i), depth+1)
+			d.b.WriteByte(',')
+		}
+		d.indent(depth)
+		d.b.WriteByte('}')
+	case reflect.Map:
+		if v.IsNil() {
+			d.b.WriteString("nil")
+			return
+		}
+		if d.visited[v.Pointer()] {
+			fmt.Fprintf(&d.b, "<cycle %s>", v.Type())
+			return
+		}
+		d.visited[v.Pointer()] = true
+		defer delete(d.visited, v.Pointer())
+		d.b.WriteString(v.Type().String())
+		if depth >= DumpDepth {
+			d.b.WriteString("{...}")
+			return
+		}
+		d.b.WriteByte('{')
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+		})
+		for _, k := range keys {
+			d.indent(depth + 1)
+			d.dump(k, depth+1)
+			d.b.WriteString(": ")
+			d.dump(v.MapIndex(k), depth+1)
+			d.b.WriteByte(',')
+		}
+		d.indent(depth)
+		d.b.WriteByte('}')
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			d.b.WriteString("nil")
+			return
+		}
+		d.b.WriteString(v.Type().String())
+		if depth >= DumpDepth {
+			d.b.WriteString("{...}")
+			return
+		}
+		d.b.WriteByte('{')
+		for i := range v.Len() {
+			d.indent(depth + 1)
+			d.dump(v.Index(i), depth+1)
+			d.b.WriteByte(',')
+		}
+		d.indent(depth)
+		d.b.WriteByte('}')
+	case reflect.String:
+		d.b.WriteString(strconv.Quote(v.String()))
+	case reflect.Bool:
+		d.b.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		d.b.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		d.b.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		d.b.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.Complex64, reflect.Complex128:
+		d.b.WriteString(strconv.FormatComplex(v.Complex(), 'g', -1, 128))
+	default:
+		fmt.Fprintf(&d.b, "%s(%#x)", v.Type(), v.Pointer())
+	}
+}
@@ -0,0 +1,175 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErasureReport is the outcome of erasing a data subject from one file.
+type ErasureReport struct {
+	// Path is the file rewritten.
+	Path string
+	// Records is the number of records read.
+	Records int
+	// Erased is the number of records removed, or hashed.
+	Erased int
+	// Verified is set once the rewritten file was read back without
+	// finding the subject.
+	Verified bool
+}
+
+// errUnkeyedHash is returned by Erase asked to hash without a keyed hash.
+var errUnkeyedHash = errors.New("log: hashing erased values needs a keyed hash set with SetHasher")
+
+// Erase rewrites the NDJSON file at path, such as an ArchiveWriter
+// partition, without the records whose key field equals value, for data
+// deletion requests. With hash, those records are kept with the value
+// replaced by its hex digest instead, using the keyed hash set by
+// SetHasher; without one Erase fails, since a plain digest of the value
+// gives it away. Files
+// ending in .gz stay gzipped. The file is replaced atomically and read back
+// to verify; files still being written must be closed first.
+//
+//	report, err := log.Erase("/var/log/app/2024/05/01.ndjson.gz", "user_id", "42", false)
+func Erase(path, key, value string, hash bool) (ErasureReport, error) {
+	report := ErasureReport{Path: path}
+	if hash && _hasher.Load() == nil {
+		return report, errUnkeyedHash
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return report, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".erase-*")
+	if err != nil {
+		return report, err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = rewriteRecords(path, tmp, func(record []byte) []byte {
+		report.Records++
+		raw, ok := field(record, key)
+		if !ok || !matches(raw, value) {
+			return record
+		}
+		report.Erased++
+		if !hash {
+			return nil
+		}
+		digest, _ := json.Marshal(hashValue(value))
+		prefix := []byte(`"` + key + `":`)
+		return bytes.ReplaceAll(record, append(prefix, raw...), append(prefix, digest...))
+	})
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	found := false
+	err = rewriteRecords(path, io.Discard, func(record []byte) []byte {
+		if raw, ok := field(record, key); ok && matches(raw, value) {
+			found = true
+		}
+		return nil
+	})
+	report.Verified = err == nil && !found
+	return report, err
+}
+
+// EraseDir applies Erase to every .ndjson and .ndjson.gz file under dir.
+func EraseDir(dir, key, value string, hash bool) ([]ErasureReport, error) {
+	var reports []ErasureReport
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if !strings.HasSuffix(path, ".ndjson") && !strings.HasSuffix(path, ".ndjson.gz") {
+			return nil
+		}
+		report, err := Erase(path, key, value, hash)
+		reports = append(reports, report)
+		return err
+	})
+	return reports, err
+}
+
+// rewriteRecords copies the records of the file at path to w through f,
+// which returns the record to write, or nil to drop it. Files ending in .gz
+// are decompressed, and w compressed.
+func rewriteRecords(path string, w io.Writer, f func(record []byte) []byte) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	var r io.Reader = src
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+		r = zr
+		zw := gzip.NewWriter(w)
+		defer zw.Close()
+		w = zw
+	}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) != 0 {
+			if out := f(line); out != nil {
+				if _, err := w.Write(out); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if zw, ok := w.(*gzip.Writer); ok {
+		return zw.Close()
+	}
+	return nil
+}
+
+// field returns the raw JSON value of key in record.
+func field(record []byte, key string) (json.RawMessage, bool) {
+	var m map[string]json.RawMessage
+	if json.Unmarshal(record, &m) != nil {
+		return nil, false
+	}
+	raw, ok := m[key]
+	return raw, ok
+}
+
+// matches reports whether raw is the JSON string or literal value.
+func matches(raw json.RawMessage, value string) bool {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s == value
+	}
+	return string(raw) == value
+}
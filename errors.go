@@ -0,0 +1,160 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// Err starts a new record carrying err, at the level its classifier
+// assigns or at error level. An error joining several others, as
+// errors.Join makes, is expanded into errors.0, errors.1, … instead of one
+// concatenated string.
+func Err(err error) (e *phuslog.Entry) {
+	class, level := classify(err)
+	if !enabled(level) {
+		return nil
+	}
+	e = bind(level)
+	if level >= phuslog.ErrorLevel {
+		e.Caller(2)
+	}
+	if class != "" {
+		e.Str("error.class", class)
+	}
+	addErr(e, err)
+	return e
+}
+
+// A Classifier assigns err a class and the level Err logs it at. It
+// returns an empty class for errors it does not know. Levels above
+// FatalLevel are taken as FatalLevel, logged as critical.
+type Classifier func(err error) (class string, level phuslog.Level)
+
+var (
+	_classifiersMu sync.RWMutex
+	_classifiers   = []Classifier{
+		func(err error) (string, phuslog.Level) {
+			if errors.Is(err, context.Canceled) {
+				return "canceled", phuslog.DebugLevel
+			}
+			return "", 0
+		},
+		func(err error) (string, phuslog.Level) {
+			if errors.Is(err, io.EOF) {
+				return "eof", phuslog.TraceLevel
+			}
+			return "", 0
+		},
+	}
+)
+
+// RegisterClassifier adds c to the classifiers consulted by Err, ahead of
+// those registered before it. By default context.Canceled is logged at
+// debug level and io.EOF at trace level.
+func RegisterClassifier(c Classifier) {
+	_classifiersMu.Lock()
+	defer _classifiersMu.Unlock()
+	_classifiers = append([]Classifier{c}, _classifiers...)
+}
+
+// classify returns the class and level of err from the first classifier
+// that knows it, or error level.
+func classify(err error) (string, phuslog.Level) {
+	if err == nil {
+		return "", phuslog.ErrorLevel
+	}
+	_classifiersMu.RLock()
+	defer _classifiersMu.RUnlock()
+	for _, c := range _classifiers {
+		if class, level := c(err); class != "" {
+			// phuslog panics on PanicLevel entries.
+			return class, min(level, phuslog.FatalLevel)
+		}
+	}
+	return "", phuslog.ErrorLevel
+}
+
+// addErr adds err to e under "error", or expanded under errors.N.
+func addErr(e *phuslog.Entry, err error) {
+	errs := joined(err)
+	if errs == nil {
+		e.Err(err)
+		return
+	}
+	for i, err := range errs {
+		e.AnErr("errors."+strconv.Itoa(i), err)
+	}
+}
+
+// joined returns the leaf errors of err if it joins several, or nil.
+func joined(err error) []error {
+	j, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil
+	}
+	var errs []error
+	for _, err := range j.Unwrap() {
+		if leaves := joined(err); leaves != nil {
+			errs = append(errs, leaves...)
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// errorsHandler expands attrs holding joined errors into groups indexed
+// by position.
+type errorsHandler struct {
+	slog.Handler
+}
+
+func (h *errorsHandler) Handle(ctx context.Context, r slog.Record) error {
+	expand := false
+	r.Attrs(func(a slog.Attr) bool {
+		expand = isJoined(a)
+		return !expand
+	})
+	if !expand {
+		return h.Handler.Handle(ctx, r)
+	}
+	return h.Handler.Handle(ctx, Rewrite(r, expandErrors))
+}
+
+func (h *errorsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	expanded := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		expanded[i], _ = expandErrors(a)
+	}
+	return &errorsHandler{h.Handler.WithAttrs(expanded)}
+}
+
+func (h *errorsHandler) WithGroup(name string) slog.Handler {
+	return &errorsHandler{h.Handler.WithGroup(name)}
+}
+
+func isJoined(a slog.Attr) bool {
+	if a.Value.Kind() != slog.KindAny {
+		return false
+	}
+	err, ok := a.Value.Any().(error)
+	return ok && joined(err) != nil
+}
+
+func expandErrors(a slog.Attr) (slog.Attr, bool) {
+	if !isJoined(a) {
+		return a, true
+	}
+	errs := joined(a.Value.Any().(error))
+	attrs := make([]slog.Attr, len(errs))
+	for i, err := range errs {
+		attrs[i] = slog.String(strconv.Itoa(i), err.Error())
+	}
+	return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}, true
+}
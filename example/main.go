@@ -7,6 +7,8 @@ import (
 )
 
 func main() {
+	log.Init()
+
 	log.Debug().Msg("debug message")
 	log.Info().Int("a", 3).Int("b", 4).Msg("hello world james")
 	log.Critical().Int("werwer", 3).Msg("werwerwer")
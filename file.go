@@ -0,0 +1,205 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+var (
+	_closersMu sync.Mutex
+	_closers   []io.Closer
+)
+
+// devMinFree is the MinFree of the file written by DevAndFile.
+const devMinFree = 64 << 20
+
+// DevAndFile keeps the console output on stderr and also writes every
+// record as JSON to the file at path, creating or appending to it. File
+// writes stop while less than 64 MiB are free on its file system, the
+// console output going on.
+func DevAndFile(path string) error {
+	w := &FileWriter{Path: path, MinFree: devMinFree}
+	if err := w.open(); err != nil {
+		return err
+	}
+	addCloser(w)
+	setWriter(&phuslog.MultiEntryWriter{
+		newConsoleWriter(os.Stderr, false),
+		w,
+	})
+	return nil
+}
+
+// FileWriter appends records to the file at Path, creating it if needed.
+// Each record is written with a single write to a file opened with
+// O_APPEND, so processes sharing the file, such as forked workers, do not
+// interleave partial lines.
+type FileWriter struct {
+	// Path is the file written.
+	Path string
+
+	// Lock also takes an advisory lock on the file around each write, for
+	// filesystems where appends from several processes are not atomic,
+	// such as NFS. It is ignored where flock is not available.
+	Lock bool
+
+	// ReopenCheck is how often to check that Path still names the open
+	// file, reopening Path when it was renamed or removed, as by external
+	// rotation with logrotate. Once a second if zero, never if negative.
+	ReopenCheck time.Duration
+
+	// MinFree stops writes while the file system holding Path has less
+	// than MinFree bytes available, so logging cannot fill the disk;
+	// other outputs, such as the console of DevAndFile, keep being
+	// written. Warnings go to the self log from twice MinFree, and when
+	// the process has used up 90% of its file descriptors. Not checked if
+	// zero, or where free space cannot be read.
+	MinFree uint64
+
+	mu      sync.Mutex
+	file    *os.File
+	checked time.Time
+	closed  bool
+
+	// spaceChecked, lowSpace and degraded track the MinFree checks.
+	spaceChecked       time.Time
+	lowSpace, degraded bool
+}
+
+// errDegraded is returned by FileWriter writes stopped for lack of space.
+var errDegraded = errors.New("log: file writes stopped, too little disk space")
+
+// spaceCheckEvery is how often FileWriter checks MinFree.
+const spaceCheckEvery = 10 * time.Second
+
+// WriteEntry implements phuslog.Writer.
+func (w *FileWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	return w.Write(e.Value())
+}
+
+// Write appends p, a complete record, to the file.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file != nil && w.moved() {
+		w.file.Close()
+		w.file = nil
+	}
+	if w.MinFree > 0 && w.checkSpace() {
+		return 0, errDegraded
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.Lock {
+		if err := lockFile(w.file); err != nil {
+			return 0, err
+		}
+		defer unlockFile(w.file)
+	}
+	return w.file.Write(p)
+}
+
+// Close closes the file. Later writes fail, so records logged after Close
+// do not reopen it.
+func (w *FileWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	return
+}
+
+func (w *FileWriter) open() error {
+	f, err := os.OpenFile(w.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.checked = time.Now()
+	return nil
+}
+
+// moved reports whether Path no longer names the open file, checking at
+// most once per ReopenCheck.
+func (w *FileWriter) moved() bool {
+	every := w.ReopenCheck
+	if every == 0 {
+		every = time.Second
+	}
+	if every < 0 || time.Since(w.checked) < every {
+		return false
+	}
+	w.checked = time.Now()
+	open, err := w.file.Stat()
+	if err != nil {
+		return false
+	}
+	cur, err := os.Stat(w.Path)
+	return err != nil || !os.SameFile(open, cur)
+}
+
+// addCloser registers c to be closed by Close.
+func addCloser(c io.Closer) {
+	_closersMu.Lock()
+	defer _closersMu.Unlock()
+	_closers = append(_closers, c)
+}
+
+// Close closes the files opened by the package for output and posts the
+// records still queued by writers shipping over HTTP, such as
+// QuickwitWriter. Call it before the program exits.
+func Close() error {
+	_closersMu.Lock()
+	defer _closersMu.Unlock()
+	var errs []error
+	for _, c := range _closers {
+		errs = append(errs, c.Close())
+	}
+	_closers = nil
+	return errors.Join(errs...)
+}
+
+// checkSpace reports whether writes are stopped for lack of space, checking
+// the free space and file descriptors at most once per spaceCheckEvery.
+func (w *FileWriter) checkSpace() bool {
+	if time.Since(w.spaceChecked) < spaceCheckEvery {
+		return w.degraded
+	}
+	w.spaceChecked = time.Now()
+	if used, limit, ok := openFiles(); ok && used*10 >= limit*9 {
+		selfLog(phuslog.WarnLevel, "file descriptors nearly exhausted", fmt.Errorf("%d of %d in use", used, limit))
+	}
+	free, ok := freeSpace(filepath.Dir(w.Path))
+	if !ok {
+		return false
+	}
+	low, degraded := free < 2*w.MinFree, free < w.MinFree
+	err := fmt.Errorf("%s: %d bytes free", w.Path, free)
+	switch {
+	case degraded && !w.degraded:
+		selfLog(phuslog.ErrorLevel, "log file writes stopped, disk nearly full", err)
+	case !degraded && w.degraded:
+		selfLog(phuslog.WarnLevel, "log file writes resumed", err)
+	case low && !w.lowSpace:
+		selfLog(phuslog.WarnLevel, "log file system low on space", err)
+	}
+	w.lowSpace, w.degraded = low, degraded
+	return degraded
+}
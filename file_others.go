@@ -0,0 +1,9 @@
+//go:build !unix
+
+package log
+
+import "os"
+
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,30 @@
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+// freeSpace returns the bytes available to the process on the file system
+// holding dir.
+func freeSpace(dir string) (uint64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false
+	}
+	return st.Bavail * uint64(st.Bsize), true
+}
+
+// openFiles returns the number of file descriptors the process has open
+// and its limit.
+func openFiles() (used, limit uint64, ok bool) {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0, 0, false
+	}
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint64(len(fds)), rl.Cur, true
+}
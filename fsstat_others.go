@@ -0,0 +1,7 @@
+//go:build !linux
+
+package log
+
+func freeSpace(dir string) (uint64, bool) { return 0, false }
+
+func openFiles() (used, limit uint64, ok bool) { return 0, 0, false }
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// _bound holds the fields bound to each goroutine, keyed by goroutine id.
+var _bound sync.Map
+
+// _binding is set once Bind is first used, so that records logged by
+// programs that never bind fields skip the lookup.
+var _binding atomic.Bool
+
+// Bind attaches the fields added by f to every record logged with the
+// leveled helpers from the current goroutine, until the returned function
+// is called, restoring the fields bound before. Fields bound earlier on
+// the goroutine are kept.
+//
+//	defer log.Bind(func(e *phuslog.Entry) { e.Int("worker", id) })()
+//
+// The fields are keyed by goroutine ID, which the runtime reuses, so fields
+// left bound by a goroutine that returned show up on a later one: always
+// defer the unbind. Goroutines started with the go statement do not get
+// the fields; Go, which starts them with the fields and unbinds them when
+// they return, is the safe form.
+func Bind(f func(e *phuslog.Entry)) (unbind func()) {
+	_binding.Store(true)
+	goid := phuslog.Goid()
+	c := bound()
+	e := phuslog.NewContext(c[:len(c):len(c)])
+	f(e)
+	_bound.Store(goid, e.Value())
+	return func() {
+		if len(c) == 0 {
+			_bound.Delete(goid)
+		} else {
+			_bound.Store(goid, c)
+		}
+	}
+}
+
+// Unbind removes all the fields bound to the current goroutine.
+func Unbind() {
+	_bound.Delete(phuslog.Goid())
+}
+
+// Go runs fn in a new goroutine that inherits the fields bound to the
+// current one.
+func Go(fn func()) {
+	c := bound()
+	go func() {
+		if len(c) != 0 {
+			_bound.Store(phuslog.Goid(), c)
+			defer Unbind()
+		}
+		fn()
+	}()
+}
+
+func bound() phuslog.Context {
+	if !_binding.Load() {
+		return nil
+	}
+	c, _ := _bound.Load(phuslog.Goid())
+	ctx, _ := c.(phuslog.Context)
+	return ctx
+}
+
+// bind starts a record at level with the fields bound to the current
+// goroutine, or returns nil if level is disabled. Critical records are
+// labeled FATL but carry ErrorLevel for routing, since phuslog exits the
+// process on fatal entries.
+func bind(level phuslog.Level) *phuslog.Entry {
+	if !enabled(level) {
+		return nil
+	}
+	e := _default.Log()
+	e.Level = level
+	if level == phuslog.FatalLevel {
+		e.Level = phuslog.ErrorLevel
+	}
+	e.Str(phuslog.LevelKey, level.String())
+	if r := resource(); len(r) != 0 {
+		e.Context(r)
+	}
+	if c := bound(); len(c) != 0 {
+		e.Context(c)
+	}
+	if s := scope(); s != "" {
+		e.Str(scopeKey, s)
+	}
+	if level == phuslog.FatalLevel {
+		addCrashBundle(e)
+	}
+	return e
+}
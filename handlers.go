@@ -0,0 +1,64 @@
+package log
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// HandlerInfo describes one output of the slog default handler chain.
+type HandlerInfo struct {
+	// Name is the name given with Named, or "".
+	Name string
+	// Type is the Go type of the handler, or of the writer for the
+	// package's own output.
+	Type string
+	// Level is the minimum level passed on, or "" if unfiltered.
+	Level string
+}
+
+// Named returns h labeled with name, as reported by Handlers.
+func Named(name string, h slog.Handler) slog.Handler {
+	return &namedHandler{h, name}
+}
+
+type namedHandler struct {
+	slog.Handler
+	name string
+}
+
+func (h *namedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &namedHandler{h.Handler.WithAttrs(attrs), h.name}
+}
+
+func (h *namedHandler) WithGroup(name string) slog.Handler {
+	return &namedHandler{h.Handler.WithGroup(name), h.name}
+}
+
+// Handlers describes where records logged through slog end up, for a debug
+// endpoint or a startup record.
+func Handlers() []HandlerInfo {
+	return describe(slog.Default().Handler(), HandlerInfo{})
+}
+
+func describe(h slog.Handler, info HandlerInfo) []HandlerInfo {
+	switch hh := h.(type) {
+	case multiHandler:
+		var infos []HandlerInfo
+		for _, c := range hh {
+			infos = append(infos, describe(c, info)...)
+		}
+		return infos
+	case *namedHandler:
+		info.Name = hh.name
+		return describe(hh.Handler, info)
+	case *levelHandler:
+		info.Level = hh.level.Level().String()
+		return describe(hh.Handler, info)
+	case *errorsHandler:
+		// Only the package's own handler is wrapped this way.
+		info.Type = fmt.Sprintf("%T", writer())
+		return []HandlerInfo{info}
+	}
+	info.Type = fmt.Sprintf("%T", h)
+	return []HandlerInfo{info}
+}
@@ -0,0 +1,40 @@
+package log
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"sync/atomic"
+)
+
+// _hasher holds the hash constructor set by SetHasher.
+var _hasher atomic.Pointer[func() hash.Hash]
+
+// SetHasher replaces SHA-256 as the hash used to pseudonymize values, as
+// Erase does, e.g. with an implementation from a FIPS-validated module.
+// nil restores SHA-256.
+//
+// A plain digest of a guessable value, such as a user ID or an email
+// address, is reversed by hashing candidates, so set a keyed hash with a
+// key kept out of the logs to really pseudonymize; Erase refuses to hash
+// until a hash is set:
+//
+//	log.SetHasher(func() hash.Hash { return hmac.New(sha256.New, key) })
+func SetHasher(h func() hash.Hash) {
+	if h == nil {
+		_hasher.Store(nil)
+		return
+	}
+	_hasher.Store(&h)
+}
+
+// hashValue returns the hex digest of s.
+func hashValue(s string) string {
+	newHash := sha256.New
+	if h := _hasher.Load(); h != nil {
+		newHash = *h
+	}
+	h := newHash()
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// HealthChecker is implemented by writers that can probe their
+// destination, such as ArchiveWriter and FileWriter.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthCheck probes each configured output and returns the results keyed
+// by the output's position and type, e.g. "1 *log.ArchiveWriter", for a
+// readiness endpoint. Outputs writing to an *os.File are checked for being
+// writable and, for regular files, for free space; other writers without a
+// HealthChecker are reported healthy.
+func HealthCheck(ctx context.Context) map[string]error {
+	var writers []phuslog.Writer
+	switch w := writer().(type) {
+	case levelOutputs:
+		for _, o := range w {
+			writers = append(writers, o.Writer)
+		}
+	case *phuslog.MultiEntryWriter:
+		writers = *w
+	default:
+		writers = []phuslog.Writer{w}
+	}
+
+	results := make(map[string]error, len(writers))
+	for i, w := range writers {
+		results[fmt.Sprintf("%d %T", i, w)] = probe(ctx, w)
+	}
+	return results
+}
+
+func probe(ctx context.Context, w phuslog.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var out io.Writer
+	switch w := w.(type) {
+	case HealthChecker:
+		return w.HealthCheck(ctx)
+	case phuslog.IOWriter:
+		out = w.Writer
+	case *phuslog.ConsoleWriter:
+		out = w.Writer
+	}
+	if f, ok := out.(*os.File); ok {
+		return probeFile(f)
+	}
+	return nil
+}
+
+// probeFile checks with an empty write that f is open for writing and, for
+// a regular file, that its file system has space left.
+func probeFile(f *os.File) error {
+	if _, err := f.Write(nil); err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Mode().IsRegular() {
+		if free, ok := freeSpace(filepath.Dir(f.Name())); ok && free == 0 {
+			return fmt.Errorf("%s: no space left on device", f.Name())
+		}
+	}
+	return nil
+}
+
+// HealthCheck reports whether a partition can be created under Dir.
+func (w *ArchiveWriter) HealthCheck(ctx context.Context) error {
+	if err := os.MkdirAll(w.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(w.Dir, ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// HealthCheck reports whether Path can be opened for appending, and fails
+// while writes are stopped by MinFree.
+func (w *FileWriter) HealthCheck(ctx context.Context) error {
+	w.mu.Lock()
+	degraded := w.degraded
+	w.mu.Unlock()
+	if degraded {
+		return errDegraded
+	}
+	f, err := os.OpenFile(w.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
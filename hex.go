@@ -0,0 +1,47 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// Hex returns a field adding data under key, truncated to limit bytes when
+// limit is positive. JSON output carries the base64 data and the original
+// length, {"base64":"aGVsbG8=","len":11}, which the console shows as a
+// canonical hex+ASCII dump.
+//
+//	log.Debug().Func(log.Hex("packet", data, 256)).Msg("received")
+func Hex(key string, data []byte, limit int) func(e *phuslog.Entry) {
+	return func(e *phuslog.Entry) {
+		n := len(data)
+		if limit > 0 && n > limit {
+			data = data[:limit]
+		}
+		e.Dict(key, phuslog.NewContext(nil).Encode("base64", data, base64.StdEncoding).Int("len", n).Value())
+	}
+}
+
+// hexBlock renders value, the JSON form of a Hex field, as a hex dump for
+// the console, reporting whether it was one.
+func hexBlock(value string) (string, bool) {
+	if !strings.HasPrefix(value, `{"base64":"`) {
+		return "", false
+	}
+	var v struct {
+		Base64 []byte `json:"base64"`
+		Len    int    `json:"len"`
+	}
+	if json.Unmarshal([]byte(value), &v) != nil {
+		return "", false
+	}
+	s := hex.Dump(v.Base64)
+	if n := v.Len - len(v.Base64); n > 0 {
+		s += fmt.Sprintf("... %d more bytes\n", n)
+	}
+	return s, true
+}
@@ -0,0 +1,157 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// Recover returns a handler that serves next and recovers its panics,
+// logging them at error level with the request, its request ID and the
+// stack, and replying 500. http.ErrAbortHandler is passed through.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, _ := WithRequestID(r.Context(), r.Header)
+		r = r.WithContext(ctx)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			bind(phuslog.ErrorLevel).
+				Context(Ctx(ctx)).
+				Str("http.method", r.Method).
+				Str("http.path", r.URL.Path).
+				Str("http.remote", r.RemoteAddr).
+				Func(panicValue(v)).
+				Bytes("stack", debug.Stack()).
+				Msg("panic serving request")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CombinedLog returns a handler that serves next and writes a line in the
+// Apache/NGINX combined log format to w for each request, for tools that
+// only parse that format.
+func CombinedLog(w io.Writer, next http.Handler) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: rw, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		user := "-"
+		if u, _, ok := r.BasicAuth(); ok && u != "" {
+			user = u
+		}
+		size := "-"
+		if sw.size > 0 {
+			size = strconv.FormatInt(sw.size, 10)
+		}
+		line := fmt.Sprintf("%s - %s [%s] \"%s\" %d %s \"%s\" \"%s\"\n",
+			host, escapeLogItem(user), start.Format("02/Jan/2006:15:04:05 -0700"),
+			escapeLogItem(r.Method+" "+r.RequestURI+" "+r.Proto), sw.status, size,
+			escapeLogItem(orDash(r.Referer())), escapeLogItem(orDash(r.UserAgent())))
+
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, line)
+	})
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// escapeLogItem escapes s as Apache does in its logs: quotes and
+// backslashes with a backslash, control characters as \n, \t and the like,
+// and other bytes outside printable ASCII as \xhh.
+func escapeLogItem(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\b':
+			b.WriteString(`\b`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\v':
+			b.WriteString(`\v`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// statusWriter records the status and body size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+	wrote  bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush flushes the response, for handlers streaming it.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wrote = true
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection, for handlers upgrading it.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
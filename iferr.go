@@ -0,0 +1,32 @@
+package log
+
+import (
+	"io"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// IfErr logs msg with err and fields at error level if err is not nil.
+func IfErr(err error, msg string, fields ...func(e *phuslog.Entry)) {
+	if err == nil {
+		return
+	}
+	e := bind(phuslog.ErrorLevel)
+	if e == nil {
+		return
+	}
+	e.Caller(2).Err(err)
+	for _, f := range fields {
+		f(e)
+	}
+	e.Msg(msg)
+}
+
+// CloseAndLog closes c and logs msg at error level if that fails:
+//
+//	defer log.CloseAndLog(f, "closing data file")
+func CloseAndLog(c io.Closer, msg string) {
+	if err := c.Close(); err != nil {
+		bind(phuslog.ErrorLevel).Caller(2).Err(err).Msg(msg)
+	}
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// Job runs fn as the scheduled job name, logging its start and its
+// completion or failure with the duration. Records, including those fn logs
+// with Ctx, carry job.name and a job.run_id unique to the run. A panic in
+// fn is logged at critical level with its stack and returned as an error.
+func Job(ctx context.Context, name string, fn func(ctx context.Context) error) (err error) {
+	ctx = withFields(ctx, func(e *phuslog.Entry) {
+		e.Str("job.name", name).Str("job.run_id", phuslog.NewXID().String())
+	})
+	start := time.Now()
+	bind(phuslog.InfoLevel).Context(Ctx(ctx)).Msg("job started")
+
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("job %s panicked: %v", name, v)
+			bind(phuslog.FatalLevel).Context(Ctx(ctx)).
+				Dur("job.duration", time.Since(start)).
+				Func(panicValue(v)).
+				Bytes("stack", debug.Stack()).
+				Msg("job panicked")
+		}
+	}()
+
+	err = fn(ctx)
+	if err != nil {
+		bind(phuslog.ErrorLevel).Context(Ctx(ctx)).
+			Dur("job.duration", time.Since(start)).
+			Err(err).
+			Msg("job failed")
+		return err
+	}
+	bind(phuslog.InfoLevel).Context(Ctx(ctx)).
+		Dur("job.duration", time.Since(start)).
+		Msg("job completed")
+	return nil
+}
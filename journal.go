@@ -0,0 +1,218 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// JournalWriter writes records to the systemd journal as native journal
+// fields rather than a JSON blob, so that journalctl can filter on them:
+// MESSAGE, PRIORITY, SYSLOG_IDENTIFIER, CODE_FILE, CODE_LINE and
+// CODE_FUNC from the caller, and every other field upper-cased, with
+// characters not allowed in field names replaced by '_' and an F_ prefix
+// on names the journal gives a meaning to, such as F_MESSAGE:
+//
+//	journalctl -t billing REQUEST_ID=abc -o verbose
+//
+// It is selected with LOG_FORMAT=journald.
+type JournalWriter struct {
+	// Socket is the journal socket, /run/systemd/journal/socket if empty.
+	Socket string
+
+	// Identifier is the SYSLOG_IDENTIFIER of the records, the name set by
+	// SetAppName or else the program name if empty.
+	Identifier string
+
+	// Level is the minimum level of the records sent, so the journal can
+	// be kept at info while the console shows debug. It is read from the
+	// level field of records given to Write. All records are sent if zero.
+	Level phuslog.Level
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// WriteEntry implements phuslog.Writer.
+func (w *JournalWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	return w.Write(e.Value())
+}
+
+// Write sends p, a complete JSON record, to the journal.
+func (w *JournalWriter) Write(p []byte) (int, error) {
+	msg, level, err := w.fields(p)
+	if err != nil {
+		return 0, err
+	}
+	if level != 0 && level < w.Level {
+		return len(p), nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		socket := w.Socket
+		if socket == "" {
+			socket = "/run/systemd/journal/socket"
+		}
+		w.conn, err = net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+		if err != nil {
+			return 0, err
+		}
+	}
+	if _, err = w.conn.Write(msg); err != nil && (errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)) {
+		err = sendJournalFile(w.conn, msg)
+	}
+	if err != nil {
+		// The journal may have restarted, so dial it again next time.
+		w.conn.Close()
+		w.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the journal.
+func (w *JournalWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+	}
+	return
+}
+
+// journalLevels maps the level strings to levels and syslog priorities.
+var journalLevels = map[string]struct {
+	level    phuslog.Level
+	priority string
+}{
+	"TRAC": {phuslog.TraceLevel, "7"},
+	"DEBG": {phuslog.DebugLevel, "7"},
+	"INFO": {phuslog.InfoLevel, "6"},
+	"NOTI": {phuslog.WarnLevel, "5"},
+	"ERRO": {phuslog.ErrorLevel, "3"},
+	"FATL": {phuslog.FatalLevel, "2"},
+	"PANC": {phuslog.PanicLevel, "0"},
+}
+
+// fields encodes record as a journal native protocol message, and returns
+// its level, or zero if it has none.
+func (w *JournalWriter) fields(record []byte) (msg []byte, level phuslog.Level, err error) {
+	d := json.NewDecoder(bytes.NewReader(record))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return nil, 0, errors.New("log: record is not a JSON object")
+	}
+	var b bytes.Buffer
+	ident := w.Identifier
+	if ident == "" {
+		ident = appName()
+	}
+	if ident == "" {
+		ident = filepath.Base(os.Args[0])
+	}
+	writeJournalField(&b, "SYSLOG_IDENTIFIER", ident)
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, 0, err
+		}
+		key := t.(string)
+		var raw json.RawMessage
+		if err := d.Decode(&raw); err != nil {
+			return nil, 0, err
+		}
+		value := string(raw)
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			value = s
+		}
+		switch key {
+		case phuslog.TimeKey:
+		case phuslog.LevelKey:
+			if l, ok := journalLevels[value]; ok {
+				level = l.level
+				writeJournalField(&b, "PRIORITY", l.priority)
+			}
+		case phuslog.MessageKey:
+			writeJournalField(&b, "MESSAGE", value)
+		case phuslog.CallerKey:
+			file, line, _ := strings.Cut(value, ":")
+			writeJournalField(&b, "CODE_FILE", file)
+			writeJournalField(&b, "CODE_LINE", line)
+		case phuslog.CallerFuncKey:
+			writeJournalField(&b, "CODE_FUNC", value)
+		default:
+			writeJournalField(&b, journalName(key), value)
+		}
+	}
+	return b.Bytes(), level, nil
+}
+
+// journalReserved are the field names the journal interprets, which
+// records must not set by accident.
+var journalReserved = map[string]bool{
+	"MESSAGE":            true,
+	"MESSAGE_ID":         true,
+	"PRIORITY":           true,
+	"CODE_FILE":          true,
+	"CODE_LINE":          true,
+	"CODE_FUNC":          true,
+	"ERRNO":              true,
+	"INVOCATION_ID":      true,
+	"USER_INVOCATION_ID": true,
+	"SYSLOG_FACILITY":    true,
+	"SYSLOG_IDENTIFIER":  true,
+	"SYSLOG_PID":         true,
+	"SYSLOG_TIMESTAMP":   true,
+	"SYSLOG_RAW":         true,
+	"DOCUMENTATION":      true,
+	"TID":                true,
+	"UNIT":               true,
+	"USER_UNIT":          true,
+}
+
+// journalName returns key as a journal field name: upper case letters,
+// digits and '_', starting with a letter, at most 64 bytes, and not one of
+// journalReserved.
+func journalName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 || name[0] < 'A' || name[0] > 'Z' || journalReserved[string(name)] {
+		name = append([]byte("F_"), name...)
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return string(name)
+}
+
+// writeJournalField appends a field in the native protocol, which gives
+// values spanning lines an explicit length.
+func writeJournalField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package log
+
+import (
+	"net"
+	"syscall"
+)
+
+func sendJournalFile(conn *net.UnixConn, msg []byte) error { return syscall.EMSGSIZE }
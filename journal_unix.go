@@ -0,0 +1,41 @@
+//go:build unix
+
+package log
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// sendJournalFile sends msg, too large for a datagram, as the descriptor of
+// an unlinked temporary file, as sd_journal_send does.
+func sendJournalFile(conn *net.UnixConn, msg []byte) error {
+	dir := "/dev/shm"
+	if _, err := os.Stat(dir); err != nil {
+		dir = ""
+	}
+	f, err := os.CreateTemp(dir, "journal-*")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	os.Remove(f.Name())
+	if _, err := f.Write(msg); err != nil {
+		return err
+	}
+	// WriteMsgUnix refuses connected datagram sockets, so send directly.
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	werr := rc.Write(func(fd uintptr) bool {
+		err = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return err != syscall.EAGAIN
+	})
+	if werr != nil {
+		return werr
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// GoroutineReportSites is how many creation sites GoroutineReport lists.
+var GoroutineReportSites = 20
+
+// GoroutineReport logs a report at notice level if more than threshold
+// goroutines are running, and reports whether it did. The report counts
+// the goroutines per creation site, busiest first, to hunt down leaks:
+//
+//	log.GoroutineReport(10000)
+func GoroutineReport(threshold int) bool {
+	n := runtime.NumGoroutine()
+	if n <= threshold {
+		return false
+	}
+	sites := map[string]int{}
+	for g := range bytes.SplitSeq(allStacks(), []byte("\n\n")) {
+		sites[creationSite(string(g))]++
+	}
+	order := slices.SortedFunc(maps.Keys(sites), func(a, b string) int {
+		return cmp.Or(sites[b]-sites[a], strings.Compare(a, b))
+	})
+	var b strings.Builder
+	for i, site := range order {
+		if i == GoroutineReportSites {
+			fmt.Fprintf(&b, "... %d more sites\n", len(order)-i)
+			break
+		}
+		fmt.Fprintf(&b, "%7d %s\n", sites[site], site)
+	}
+	bind(phuslog.WarnLevel).
+		Int("goroutines", n).
+		Int("goroutines.threshold", threshold).
+		Str("goroutines.sites", b.String()).
+		Msg("goroutine count over threshold")
+	return true
+}
+
+// creationSite returns the function and position that created the
+// goroutine whose stack is g, or its top function if it has no creator.
+func creationSite(g string) string {
+	i := strings.LastIndex(g, "\ncreated by ")
+	if i < 0 {
+		// Goroutines such as main have no creator.
+		_, top, _ := strings.Cut(g, "\n")
+		top, _, _ = strings.Cut(top, "\n")
+		if j := strings.LastIndex(top, "("); j > 0 {
+			top = top[:j]
+		}
+		return top
+	}
+	lines := strings.SplitN(g[i+len("\ncreated by "):], "\n", 3)
+	fn, _, _ := strings.Cut(lines[0], " in goroutine ")
+	if len(lines) < 2 {
+		return fn
+	}
+	pos, _, _ := strings.Cut(strings.TrimSpace(lines[1]), " +")
+	return fn + " at " + pos
+}
@@ -0,0 +1,133 @@
+package log
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// SetLevel sets the minimum level of the records written, for the leveled
+// helpers and slog alike. Everything from trace up is written by default.
+func SetLevel(level phuslog.Level) {
+	lazyInit()
+	_default.SetLevel(level)
+}
+
+// slogLevel is the current level as a slog.Leveler, so the slog default
+// handler follows SetLevel.
+type slogLevel struct{}
+
+func (slogLevel) Level() slog.Level {
+	if triggered() {
+		return slog.LevelDebug - 4
+	}
+	switch currentLevel() {
+	case phuslog.TraceLevel:
+		return slog.LevelDebug - 4
+	case phuslog.DebugLevel:
+		return slog.LevelDebug
+	case phuslog.InfoLevel:
+		return slog.LevelInfo
+	case phuslog.WarnLevel:
+		return slog.LevelWarn
+	case phuslog.ErrorLevel:
+		return slog.LevelError
+	}
+	return slog.LevelError + 4
+}
+
+// TraceFor writes everything from trace up for d, then restores the level,
+// to capture a short burst of diagnostics in production.
+func TraceFor(d time.Duration) {
+	_burstMu.Lock()
+	defer _burstMu.Unlock()
+	startBurst()
+	if _burstTimer != nil {
+		_burstTimer.Stop()
+	}
+	_burstTimer = time.AfterFunc(d, endBurst)
+}
+
+// TraceNext writes everything from trace up for the next n records, then
+// restores the level.
+func TraceNext(n int) {
+	_burstMu.Lock()
+	defer _burstMu.Unlock()
+	startBurst()
+	_burstLeft.Store(int64(n))
+}
+
+var (
+	_burstMu    sync.Mutex
+	_bursting   bool
+	_burstLevel phuslog.Level
+	_burstTimer *time.Timer
+	// _burstLeft counts down the records of a TraceNext burst.
+	_burstLeft atomic.Int64
+)
+
+// startBurst lowers the level to trace, keeping the level to restore if a
+// burst is already running.
+func startBurst() {
+	if !_bursting {
+		_bursting = true
+		_burstLevel = currentLevel()
+	}
+	SetLevel(phuslog.TraceLevel)
+}
+
+// endBurst restores the level unless it was changed during the burst.
+func endBurst() {
+	_burstMu.Lock()
+	defer _burstMu.Unlock()
+	if !_bursting {
+		return
+	}
+	_bursting = false
+	_burstLeft.Store(0)
+	if _burstTimer != nil {
+		_burstTimer.Stop()
+		_burstTimer = nil
+	}
+	if currentLevel() == phuslog.TraceLevel {
+		SetLevel(_burstLevel)
+	}
+}
+
+// countBurst counts a record against a TraceNext burst.
+func countBurst() {
+	if _burstLeft.Load() > 0 && _burstLeft.Add(-1) == 0 {
+		endBurst()
+	}
+}
+
+func currentLevel() phuslog.Level {
+	lazyInit()
+	return phuslog.Level(atomic.LoadUint32((*uint32)(&_default.Level)))
+}
+
+func enabled(level phuslog.Level) bool {
+	return currentLevel() <= level || triggered()
+}
+
+// TraceEnabled reports whether trace records are written, to guard
+// building expensive arguments.
+func TraceEnabled() bool { return enabled(phuslog.TraceLevel) }
+
+// DebugEnabled reports whether debug records are written.
+func DebugEnabled() bool { return enabled(phuslog.DebugLevel) }
+
+// InfoEnabled reports whether info records are written.
+func InfoEnabled() bool { return enabled(phuslog.InfoLevel) }
+
+// NoticeEnabled reports whether notice records are written.
+func NoticeEnabled() bool { return enabled(phuslog.WarnLevel) }
+
+// ErrorEnabled reports whether error records are written.
+func ErrorEnabled() bool { return enabled(phuslog.ErrorLevel) }
+
+// CriticalEnabled reports whether critical records are written.
+func CriticalEnabled() bool { return enabled(phuslog.FatalLevel) }
@@ -0,0 +1,7 @@
+//go:build log_library
+
+package log
+
+// libraryMode is set by the log_library build tag: Init then leaves the
+// slog default alone, whoever calls it.
+const libraryMode = true
@@ -0,0 +1,5 @@
+//go:build !log_library
+
+package log
+
+const libraryMode = false
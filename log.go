@@ -4,6 +4,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"sync"
 
 	stdlog "log"
 
@@ -32,7 +33,17 @@ var _defaultOutput io.Writer = os.Stdout
 //   I0314 10:22:05.123456 12345 main.go:42] hello world
 //   W0314 10:22:05.123457 12345 main.go:43] something bad
 
-func init() {
+// _initOnce guards the setup of the package, which happens on first use or
+// in Init.
+var _initOnce sync.Once
+
+// lazyInit sets the package up with the defaults unless it already is.
+// Everything reading or changing the configuration calls it first.
+func lazyInit() {
+	_initOnce.Do(setup)
+}
+
+func setup() {
 	phuslog.TimeKey = "ts"
 	phuslog.CallerKey = "src"
 	phuslog.CallerFuncKey = "func"
@@ -56,23 +67,12 @@ func init() {
 	// 	phuslog.PanicLevel: "PANIC",
 	// }
 
-	var writer phuslog.Writer
-	switch os.Getenv("LOG_FORMAT") {
-	case "json":
-		writer = phuslog.IOWriter{Writer: _defaultOutput}
-	default:
-		writer = &phuslog.ConsoleWriter{
-			Formatter: phuslog.LogfmtFormatter{"ts"}.Formatter,
-			Writer:    os.Stderr,
-		}
-	}
-
 	_default = phuslog.Logger{
 		// TimeFormat: "01-02 15:04:05",
 		// TimeFormat: time.DateTime,
 		// TimeFormat: time.RFC3339Nano,
 		TimeFormat: phuslog.TimeFormatUnixMs,
-		Writer:     writer,
+		Writer:     &output{formatWriter(os.Getenv("LOG_FORMAT"))},
 
 		// Writer: &phuslog.ConsoleWriter{
 		// 	Writer:         os.Stdout,
@@ -80,18 +80,163 @@ func init() {
 		// 	QuoteString:    true,
 		// 	EndWithMessage: true,
 		// },
-		Level: phuslog.DebugLevel,
+		Level: phuslog.TraceLevel,
 		// Caller: 2,
 	}
+}
+
+// formatWriter returns the writer for a LOG_FORMAT value.
+func formatWriter(format string) phuslog.Writer {
+	switch format {
+	case "json":
+		return phuslog.IOWriter{Writer: _defaultOutput}
+	case "json-pretty":
+		return &prettyWriter{w: _defaultOutput}
+	case "docker":
+		return &dockerWriter{w: _defaultOutput, stream: "stdout"}
+	case "cri":
+		return &criWriter{w: _defaultOutput, stream: "stdout"}
+	case "journald":
+		return &JournalWriter{}
+	}
+	if w := serviceWriter(); w != nil {
+		return w
+	}
+	return newConsoleWriter(os.Stderr, false)
+}
+
+// An Option configures the package in Init.
+type Option func(*options)
+
+type options struct {
+	format    string
+	hasFormat bool
+	writer    io.Writer
+	level     phuslog.Level
+}
+
+// WithFormat selects the output format as LOG_FORMAT does: "json",
+// "json-pretty", "docker", "cri", "journald", or "" for the console.
+func WithFormat(format string) Option {
+	return func(o *options) { o.format, o.hasFormat = format, true }
+}
+
+// WithWriter writes JSON records to w.
+func WithWriter(w io.Writer) Option {
+	return func(o *options) { o.writer = w }
+}
+
+// WithLevel sets the minimum level of the records written.
+func WithLevel(level phuslog.Level) Option {
+	return func(o *options) { o.level = level }
+}
+
+// Init configures the package with opts and installs it as the slog
+// default. It is meant to be called early in main. Without it, the package
+// sets itself up on first use, as LOG_FORMAT says, but leaves slog alone,
+// so importing it from a library has no effect on the program until it
+// logs. Building with the log_library tag keeps Init from touching the
+// slog default too, for programs whose dependencies call it.
+//
+//	log.Init(log.WithFormat("json"), log.WithLevel(phuslog.InfoLevel))
+func Init(opts ...Option) {
+	lazyInit()
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	switch {
+	case o.writer != nil:
+		_default.Writer = &output{phuslog.IOWriter{Writer: o.writer}}
+	case o.hasFormat:
+		_default.Writer = &output{formatWriter(o.format)}
+	}
+	if o.level != 0 {
+		SetLevel(o.level)
+	}
+	if libraryMode {
+		return
+	}
+	_slogHandler = newSlogHandler()
+	slog.SetDefault(slog.New(_slogHandler))
+}
 
-	slog.SetDefault(slog.New(_default.Slog().Handler()))
+// newSlogHandler returns the slog handler writing through _default. Its
+// copy of _default lets everything through, leaving the level to be
+// checked against _default as it changes.
+func newSlogHandler() slog.Handler {
+	l := _default
+	l.Level = phuslog.TraceLevel
+	l.Context = resource()
+	return &levelHandler{&errorsHandler{l.Slog().Handler()}, slogLevel{}}
+}
+
+// _slogHandler is the slog handler installed as the slog default.
+var _slogHandler slog.Handler
+
+// resetSlog reinstalls the slog default so it picks up changes to _default,
+// unless the application has since replaced it with a handler of its own.
+func resetSlog() {
+	if _slogHandler == nil || slog.Default().Handler() != _slogHandler {
+		return
+	}
+	if libraryMode {
+		return
+	}
+	_slogHandler = newSlogHandler()
+	slog.SetDefault(slog.New(_slogHandler))
 }
 
 func SetWriter(w io.Writer) {
-	_default.Writer = phuslog.IOWriter{Writer: w}
+	setWriter(phuslog.IOWriter{Writer: w})
+}
+
+// Time formats accepted by SetTimeFormat besides time layouts such as
+// time.RFC3339Nano. Nanosecond epochs are not supported by the encoder.
+const (
+	TimeFormatUnix   = phuslog.TimeFormatUnix
+	TimeFormatUnixMs = phuslog.TimeFormatUnixMs
+)
+
+// SetTimeFormat sets how the time field is encoded: as a numeric epoch with
+// TimeFormatUnix or TimeFormatUnixMs (the default), or as a string with any
+// time layout.
+func SetTimeFormat(format string) {
+	lazyInit()
+	_default.TimeFormat = format
+	resetSlog()
 }
 
+// FieldNames are the JSON keys of the fields every record carries, in
+// place of ts, level, msg and src. Empty names keep the default key.
+type FieldNames struct {
+	Time    string
+	Level   string
+	Message string
+	Caller  string
+}
+
+// SetFieldNames renames the core JSON keys of the records written to every
+// output, e.g. to "@timestamp", "severity" and "message"; Output.FieldNames
+// overrides it for one output. Console and journal outputs keep the default
+// keys, which they read back. The keys are rewritten as records are
+// written, so other phuslu/log loggers in the program are not affected.
+func SetFieldNames(n FieldNames) {
+	setRewrite(func() { _fieldNames = n })
+}
+
+// WithCaller adds a caller position to every record: that of the code
+// calling the helper with 2, further up the stack with larger n. Negative
+// n gives the full path.
 func WithCaller(n int) {
+	lazyInit()
+	// Records are started in bind, one frame below the helpers.
+	switch {
+	case n > 0:
+		n++
+	case n < 0:
+		n--
+	}
 	_default.Caller = n
 }
 
@@ -99,54 +244,54 @@ var Println = stdlog.Println
 var Printf = Infof
 
 func Trace() (e *phuslog.Entry) {
-	return _default.Log().Str("level", "TRAC")
+	return bind(phuslog.TraceLevel)
 }
 
 func Tracef(format string, args ...any) {
-	_default.Log().Str("level", "TRAC").Msgf(format, args...)
+	bind(phuslog.TraceLevel).Msgf(format, args...)
 }
 
 func Debug() (e *phuslog.Entry) {
-	return _default.Log().Str("level", "DEBG")
+	return bind(phuslog.DebugLevel)
 }
 
 func Debugf(format string, args ...any) {
-	_default.Log().Str("level", "DEBG").Msgf(format, args...)
+	bind(phuslog.DebugLevel).Msgf(format, args...)
 }
 
 func Info() (e *phuslog.Entry) {
-	return _default.Log().Str("level", "INFO")
+	return bind(phuslog.InfoLevel)
 }
 
 func Infof(format string, args ...any) {
-	_default.Log().Str("level", "INFO").Msgf(format, args...)
+	bind(phuslog.InfoLevel).Msgf(format, args...)
 }
 
 func Notice() (e *phuslog.Entry) {
-	return _default.Log().Str("level", "NOTI")
+	return bind(phuslog.WarnLevel)
 }
 
 func Noticef(format string, args ...any) {
-	_default.Log().Str("level", "NOTI").Msgf(format, args...)
+	bind(phuslog.WarnLevel).Msgf(format, args...)
 }
 
 // ["OFF", "CRIT", "ERRO", "WARN", "INFO", "DEBG", "TRCE"];
 func Error() (e *phuslog.Entry) {
-	return _default.Log().Str("level", "ERRO").Caller(2)
+	return bind(phuslog.ErrorLevel).Caller(2)
 }
 
 func Errorf(format string, args ...any) {
-	_default.Log().Str("level", "ERRO").Caller(2).Msgf(format, args...)
+	bind(phuslog.ErrorLevel).Caller(2).Msgf(format, args...)
 }
 
 func Critical() (e *phuslog.Entry) {
-	return _default.Log().Str("level", "FATL").Caller(2)
+	return bind(phuslog.FatalLevel).Caller(2)
 }
 
 func Criticalf(format string, args ...any) {
-	_default.Log().Str("level", "FATL").Caller(2).Msgf(format, args...)
+	bind(phuslog.FatalLevel).Caller(2).Msgf(format, args...)
 }
 
 func Print(args ...any) {
-	_default.Log().Str("level", "INFO").Msgs(args...)
+	bind(phuslog.InfoLevel).Msgs(args...)
 }
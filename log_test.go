@@ -0,0 +1,1731 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	h := http.Header{}
+	h.Set(RequestIDHeader, "abc")
+	ctx, id := WithRequestID(context.Background(), h)
+	if id != "abc" || RequestID(ctx) != "abc" {
+		t.Fatalf("request id = %q, want abc", id)
+	}
+
+	Info().Context(Ctx(ctx)).Msg("handled")
+	if !strings.Contains(buf.String(), `"request_id":"abc"`) {
+		t.Fatalf("missing request_id: %s", buf.String())
+	}
+
+	out := http.Header{}
+	InjectRequestID(ctx, out)
+	if out.Get(RequestIDHeader) != "abc" {
+		t.Fatalf("injected header = %q", out.Get(RequestIDHeader))
+	}
+
+	if _, id := WithRequestID(context.Background(), nil); id == "" {
+		t.Fatal("expected generated request id")
+	}
+
+	buf.Reset()
+	again, id := WithRequestID(ctx, http.Header{})
+	Info().Context(Ctx(again)).Msg("again")
+	if id != "abc" || strings.Count(buf.String(), `"request_id"`) != 1 {
+		t.Fatalf("request id replaced: %q %s", id, buf.String())
+	}
+}
+
+func TestTenant(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	defer SetTenantResolver(nil)
+
+	SetTenantResolver(func(ctx context.Context) string { return "resolved" })
+	Info().Context(Ctx(context.Background())).Msg("resolved")
+	Info().Context(Ctx(WithTenant(context.Background(), "acme"))).Msg("explicit")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"tenant":"resolved"`) {
+		t.Fatalf("missing resolved tenant: %s", lines[0])
+	}
+	if strings.Count(lines[1], `"tenant"`) != 1 || !strings.Contains(lines[1], `"tenant":"acme"`) {
+		t.Fatalf("unexpected tenant fields: %s", lines[1])
+	}
+}
+
+func TestSetFieldNames(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	defer SetFieldNames(FieldNames{})
+
+	SetFieldNames(FieldNames{Time: "@timestamp", Level: "severity", Message: "message"})
+	Info().Msg("renamed")
+	for _, key := range []string{`{"@timestamp":`, `"severity":"INFO"`, `"message":"renamed"}`} {
+		if !strings.Contains(buf.String(), key) {
+			t.Fatalf("missing %s: %s", key, buf.String())
+		}
+	}
+	if phuslog.TimeKey != "ts" {
+		t.Fatalf("phuslog keys changed: %s", phuslog.TimeKey)
+	}
+
+	var console, file bytes.Buffer
+	SetOutputs(
+		Output{Writer: NewConsoleWriter(&console)},
+		Output{Writer: phuslog.IOWriter{Writer: &file}, FieldNames: FieldNames{Message: "text"}},
+	)
+	defer SetWriter(io.Discard)
+	Info().Msg("per output")
+	if !strings.Contains(console.String(), `level=INFO "per output"`) {
+		t.Fatalf("console: %s", console.String())
+	}
+	if !strings.Contains(file.String(), `"level":"INFO"`) || !strings.Contains(file.String(), `"text":"per output"`) {
+		t.Fatalf("file: %s", file.String())
+	}
+}
+
+func TestSetTimeFormat(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	defer SetTimeFormat(TimeFormatUnixMs)
+
+	SetTimeFormat(TimeFormatUnix)
+	Info().Msg("epoch")
+	if !strings.HasPrefix(buf.String(), `{"ts":1`) {
+		t.Fatalf("expected numeric time: %s", buf.String())
+	}
+
+	buf.Reset()
+	SetTimeFormat(time.RFC3339)
+	slog.Info("layout")
+	if !strings.HasPrefix(buf.String(), `{"ts":"`) {
+		t.Fatalf("expected string time: %s", buf.String())
+	}
+}
+
+func TestSetOmit(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	defer SetOmit(0)
+
+	SetOmit(OmitEmptyString | OmitZeroNumber | OmitNil | OmitEmptyGroup)
+	slog.Info("sparse", "s", "", "n", 0, "p", nil, slog.Group("g", "s", ""), "kept", 1)
+	for _, key := range []string{`"s"`, `"n"`, `"p"`, `"g"`} {
+		if strings.Contains(buf.String(), key) {
+			t.Fatalf("%s not omitted: %s", key, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), `"kept":1`) {
+		t.Fatalf("missing kept: %s", buf.String())
+	}
+
+	buf.Reset()
+	Info().Str("s", "").Int("n", 0).Float64("f", 0).Dict("g", phuslog.NewContext(nil).Str("s", "").Value()).Int("kept", 1).Msg("")
+	for _, key := range []string{`"s"`, `"n"`, `"f"`, `"g"`} {
+		if strings.Contains(buf.String(), key) {
+			t.Fatalf("%s not omitted: %s", key, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), `"kept":1`) {
+		t.Fatalf("missing kept: %s", buf.String())
+	}
+
+	SetOmit(0)
+	var sparse, full bytes.Buffer
+	SetOutputs(
+		Output{Writer: phuslog.IOWriter{Writer: &sparse}, Omit: OmitEmptyString},
+		Output{Writer: phuslog.IOWriter{Writer: &full}},
+	)
+	defer SetWriter(io.Discard)
+	Info().Str("s", "").Msg("per output")
+	if strings.Contains(sparse.String(), `"s"`) || !strings.Contains(full.String(), `"s":""`) {
+		t.Fatalf("per-output omit: %s / %s", sparse.String(), full.String())
+	}
+}
+
+func TestPrettyWriter(t *testing.T) {
+	var buf bytes.Buffer
+	setWriter(&prettyWriter{w: &buf})
+	defer SetWriter(io.Discard)
+
+	Info().Int("b", 2).Int("a", 1).Msg("pretty")
+	want := "{\n  \"a\": 1,\n  \"b\": 2,\n"
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestConsoleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewConsoleHandler(&buf, &ConsoleOptions{Level: slog.LevelInfo}))
+	l.Debug("hidden")
+	l.Info("shown", "a", 1)
+	if strings.Contains(buf.String(), "hidden") {
+		t.Fatalf("debug record not filtered: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `a=1 "shown"`) {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestMultiHandler(t *testing.T) {
+	var debug, info bytes.Buffer
+	l := slog.New(NewMultiHandler(
+		NewConsoleHandler(&debug, nil),
+		NewConsoleHandler(&info, &ConsoleOptions{Level: slog.LevelInfo}),
+	))
+	l.Debug("debug")
+	l.With("a", 1).Info("info")
+	if !strings.Contains(debug.String(), "debug") || !strings.Contains(debug.String(), `a=1 "info"`) {
+		t.Fatalf("unexpected debug output: %s", debug.String())
+	}
+	if strings.Contains(info.String(), "debug") || !strings.Contains(info.String(), `a=1 "info"`) {
+		t.Fatalf("unexpected info output: %s", info.String())
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	old := slog.Default()
+	defer slog.SetDefault(old)
+
+	slog.SetDefault(slog.New(NewMultiHandler(
+		old.Handler(),
+		Named("console", NewConsoleHandler(io.Discard, &ConsoleOptions{Level: slog.LevelWarn})),
+	)))
+	infos := Handlers()
+	if len(infos) != 2 {
+		t.Fatalf("got %d handlers, want 2: %+v", len(infos), infos)
+	}
+	if infos[1].Name != "console" || infos[1].Level != "WARN" {
+		t.Fatalf("unexpected info: %+v", infos[1])
+	}
+}
+
+func TestBind(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	unbind := Bind(func(e *phuslog.Entry) { e.Int("worker", 7) })
+	done := make(chan struct{})
+	Go(func() {
+		Info().Msg("child")
+		close(done)
+	})
+	<-done
+	unbindJob := Bind(func(e *phuslog.Entry) { e.Int("job", 1) })
+	Info().Msg("job")
+	unbindJob()
+	Info().Msg("worker")
+	unbind()
+	Info().Msg("unbound")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"worker":7`) {
+		t.Fatalf("child did not inherit fields: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"worker":7,"job":1`) {
+		t.Fatalf("fields not added: %s", lines[1])
+	}
+	if !strings.Contains(lines[2], `"worker":7`) || strings.Contains(lines[2], "job") {
+		t.Fatalf("earlier fields not restored: %s", lines[2])
+	}
+	if strings.Contains(lines[3], "worker") {
+		t.Fatalf("fields not unbound: %s", lines[3])
+	}
+}
+
+func TestDump(t *testing.T) {
+	type node struct {
+		Name  string
+		Next  *node
+		Tags  map[string]int
+		inner int
+	}
+	n := &node{Name: "a", Tags: map[string]int{"y": 2, "x": 1}, inner: 3}
+	n.Next = n
+
+	want := `&log.node{
+  Name: "a",
+  Next: <cycle *log.node>,
+  Tags: map[string]int{
+    "x": 1,
+    "y": 2,
+  },
+}`
+	if got := Sdump(n); got != want {
+		t.Fatalf("Sdump =\n%s\nwant\n%s", got, want)
+	}
+
+	var buf bytes.Buffer
+	setWriter(newConsoleWriter(&buf, false))
+	defer SetWriter(io.Discard)
+	Dump("cfg", map[string]int{"a": 1})
+	if !strings.Contains(buf.String(), "\n  cfg:\n    map[string]int{\n") {
+		t.Fatalf("unexpected console output:\n%s", buf.String())
+	}
+}
+
+func TestHex(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	Info().Func(Hex("packet", []byte("hello world"), 5)).Msg("json")
+	if !strings.Contains(buf.String(), `"packet":{"base64":"aGVsbG8=","len":11}`) {
+		t.Fatalf("unexpected JSON output: %s", buf.String())
+	}
+
+	buf.Reset()
+	setWriter(&phuslog.MultiEntryWriter{newConsoleWriter(&buf, false)})
+	defer SetWriter(io.Discard)
+	Info().Func(Hex("packet", []byte("hello world"), 5)).Msg("console")
+	if !strings.Contains(buf.String(), "  packet:\n    00000000  68 65 6c 6c 6f") ||
+		!strings.Contains(buf.String(), "    ... 6 more bytes\n") {
+		t.Fatalf("unexpected console output:\n%s", buf.String())
+	}
+}
+
+func TestCtxDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	if c := string(Ctx(ctx)); strings.Contains(c, "ctx.") {
+		t.Fatalf("distant deadline annotated: %s", c)
+	}
+	cancel()
+	c := string(Ctx(ctx))
+	if !strings.Contains(c, `"ctx.deadline_remaining":`) || !strings.Contains(c, `"ctx.err":"context canceled"`) {
+		t.Fatalf("missing deadline fields: %s", c)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	ctx := context.Background()
+	errFail := errors.New("refused")
+	for attempt := 1; attempt <= 3; attempt++ {
+		Retry(ctx, "connect", attempt, errFail, time.Second, time.Duration(attempt-1)*time.Second)
+	}
+	Retry(ctx, "connect", 4, nil, 0, 3*time.Second)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[2], `"level":"NOTI"`) {
+		t.Fatalf("third attempt not escalated: %s", lines[2])
+	}
+	if !strings.Contains(lines[3], `"retry.total_delay":3000`) {
+		t.Fatalf("unexpected total delay: %s", lines[3])
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	c := WebSocket(httptest.NewRequest("GET", "/ws", nil))
+	c.Received()
+	c.Sent()
+	c.Sent()
+	c.Closed(1000, nil)
+	c.Closed(1011, errors.New("reset"))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"ws.conn_id":"`) {
+		t.Fatalf("got %d records, want 3: %s", len(lines), buf.String())
+	}
+	_, id, _ := strings.Cut(lines[0], `"ws.conn_id":"`)
+	id, _, _ = strings.Cut(id, `"`)
+	id = `"ws.conn_id":"` + id + `"`
+	for i, want := range [][]string{
+		{`"level":"INFO"`, `"http.path":"/ws"`, `"msg":"websocket upgraded"`},
+		{`"level":"INFO"`, `"ws.close_code":1000,"ws.received":1,"ws.sent":2`, `"msg":"websocket closed"`},
+		{`"level":"ERRO"`, `"ws.close_code":1011`, `"error":"reset"`},
+	} {
+		for _, s := range append(want, id) {
+			if !strings.Contains(lines[i], s) {
+				t.Fatalf("record %d missing %s: %s", i, s, lines[i])
+			}
+		}
+	}
+}
+
+func TestConsume(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	Consume(context.Background(), "orders", 3, 42, []byte("k1")).Done(nil)
+	m := Consume(context.Background(), "orders", 3, 43, []byte("k2"))
+	Info().Context(Ctx(m.Context())).Msg("charging")
+	m.Done(errors.New("declined"))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d records, want 3: %s", len(lines), buf.String())
+	}
+	for i, want := range [][]string{
+		{`"level":"DEBG"`, `"queue.topic":"orders","queue.partition":3,"queue.offset":42,"queue.key":"k1"`, `"queue.latency":`, `"msg":"message processed"`},
+		{`"level":"INFO"`, `"queue.offset":43`, `"msg":"charging"`},
+		{`"level":"ERRO"`, `"queue.offset":43`, `"error":"declined"`, `"msg":"message failed"`},
+	} {
+		for _, s := range want {
+			if !strings.Contains(lines[i], s) {
+				t.Fatalf("record %d missing %s: %s", i, s, lines[i])
+			}
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/x", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	for _, s := range []string{`"level":"ERRO"`, `"panic":"boom"`, `"http.path":"/x"`, `"request_id":`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("missing %s: %s", s, buf.String())
+		}
+	}
+}
+
+func TestJob(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	err := Job(context.Background(), "cleanup", func(ctx context.Context) error {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("expected panic to be returned as error")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"msg":"job panicked"`) || !strings.Contains(lines[1], `"job.run_id":`) {
+		t.Fatalf("unexpected records: %s", buf.String())
+	}
+}
+
+type panicState struct {
+	Shard int `json:"shard"`
+}
+
+func TestPanicValue(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	Job(context.Background(), "wrapped", func(ctx context.Context) error {
+		panic(fmt.Errorf("load: %w", os.ErrNotExist))
+	})
+	Job(context.Background(), "struct", func(ctx context.Context) error {
+		panic(&panicState{Shard: 3})
+	})
+
+	got := buf.String()
+	for _, s := range []string{
+		`"panic.type":"*fmt.wrapError","panic.wrapped":[{"type":"*errors.errorString","msg":"file does not exist"}]`,
+		`"panic.type":"*log.panicState","panic.value":{"shard":3}`,
+	} {
+		if !strings.Contains(got, s) {
+			t.Fatalf("missing %s: %s", s, got)
+		}
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	var buf syncBuffer
+	SetWriter(&buf)
+
+	done := Watchdog(context.Background(), "load", 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	done()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"level":"NOTI"`) || !strings.Contains(lines[1], `"op.slow":true`) {
+		t.Fatalf("unexpected records: %s", buf.String())
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for records written from other goroutines.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestWatchStalls(t *testing.T) {
+	var buf syncBuffer
+	SetWriter(&buf)
+
+	stop := WatchStalls(10*time.Millisecond, false)
+	time.Sleep(100 * time.Millisecond)
+	stop()
+
+	if n := strings.Count(buf.String(), "no records logged"); n != 1 {
+		t.Fatalf("got %d stall records, want 1: %s", n, buf.String())
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	var b Builder
+	b.Add(Any("a", 1)).AddIf(false, Any("b", 2)).AddIf(true, Any("c", "x"))
+	Info().Func(b.Fields).Msg("built")
+	if !strings.Contains(buf.String(), `"a":1,"c":"x"`) || strings.Contains(buf.String(), `"b"`) {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestPreset(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	RegisterPreset("httpreq", "method", "path", "status")
+	Info().Func(WithPreset("httpreq", "GET", "/", 200)).Msg("served")
+	Info().Func(WithPreset("httpreq", "GET")).Msg("bad")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"method":"GET","path":"/","status":200`) {
+		t.Fatalf("unexpected output: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"!BADPRESET"`) {
+		t.Fatalf("mismatch not reported: %s", lines[1])
+	}
+}
+
+func TestDevAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.json")
+	if err := DevAndFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer SetWriter(io.Discard)
+
+	Trace().Msg("to file")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"level":"TRAC"`) {
+		t.Fatalf("unexpected file contents: %s", b)
+	}
+}
+
+func TestSetOutputs(t *testing.T) {
+	var console, file, pager bytes.Buffer
+	SetOutputs(
+		Output{Writer: NewConsoleWriter(&console), Level: phuslog.TraceLevel},
+		Output{Writer: phuslog.IOWriter{Writer: &file}, Level: phuslog.InfoLevel},
+		Output{Writer: phuslog.IOWriter{Writer: &pager}, Level: phuslog.FatalLevel},
+	)
+	defer SetWriter(io.Discard)
+
+	Debug().Msg("debug")
+	Critical().Msg("critical")
+	slog.Info("info")
+
+	if n := strings.Count(console.String(), "\n"); n != 3 {
+		t.Fatalf("console got %d records, want 3: %s", n, console.String())
+	}
+	if strings.Contains(file.String(), "debug") || strings.Count(file.String(), "\n") != 2 {
+		t.Fatalf("unexpected file records: %s", file.String())
+	}
+	if !strings.Contains(pager.String(), "critical") || strings.Count(pager.String(), "\n") != 1 {
+		t.Fatalf("unexpected critical records: %s", pager.String())
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	SetWriter(io.Discard)
+	ch := make(chan Record, 1)
+	unsubscribe := Subscribe(ch)
+
+	start := time.Now().Truncate(time.Millisecond)
+	Info().Int("n", 1).Msg("first")
+	Info().Msg("dropped")
+	unsubscribe()
+	Info().Msg("after")
+
+	got := <-ch
+	if got.Message != "first" || got.Level != phuslog.InfoLevel || got.Fields["n"] != json.Number("1") ||
+		got.Time.Before(start) || !strings.Contains(string(got.JSON), `"msg":"first"`) {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+	select {
+	case got := <-ch:
+		t.Fatalf("unexpected record: %s", got.JSON)
+	default:
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)
+	r.AddAttrs(slog.String("keep", "a"), slog.String("drop", "b"))
+	nr := Rewrite(r, func(a slog.Attr) (slog.Attr, bool) {
+		a.Key = "x." + a.Key
+		return a, a.Key != "x.drop"
+	})
+
+	var buf bytes.Buffer
+	if err := Reemit(context.Background(), NewConsoleHandler(&buf, nil), nr); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `x.keep="a"`) || strings.Contains(buf.String(), "drop") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+	if r.NumAttrs() != 2 {
+		t.Fatalf("original record modified")
+	}
+}
+
+func TestReadStats(t *testing.T) {
+	SetWriter(io.Discard)
+	before := ReadStats().Records
+	for range statsSampleEvery {
+		Info().Msg("counted")
+	}
+	if got := ReadStats().Records - before; got != statsSampleEvery {
+		t.Fatalf("counted %d records, want %d", got, statsSampleEvery)
+	}
+}
+
+func TestSampled(t *testing.T) {
+	SetWriter(io.Discard)
+	defer _sampleKeep.Store(1)
+
+	_sampleKeep.Store(4)
+	var kept int
+	for range 100 {
+		e := Info()
+		if !sampled(e) {
+			kept++
+		}
+		e.Discard()
+	}
+	if kept != 25 {
+		t.Fatalf("kept %d of 100 info records, want 25", kept)
+	}
+	e := Error()
+	if sampled(e) {
+		t.Fatal("error record dropped")
+	}
+	e.Discard()
+}
+
+func TestArchiveWriter(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	dir := t.TempDir()
+	// A partition left uncompressed by an earlier run.
+	os.MkdirAll(filepath.Join(dir, "2026/01"), 0o755)
+	os.WriteFile(filepath.Join(dir, "2026/01/01.ndjson"), []byte("{\"msg\":\"left\"}\n"), 0o644)
+	w := &ArchiveWriter{Dir: dir, Compress: true}
+
+	timeNow = func() time.Time { return time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC) }
+	w.Write([]byte("{\"msg\":\"a\"}\n"))
+	timeNow = func() time.Time { return time.Date(2026, 1, 3, 3, 0, 0, 0, time.UTC) }
+	w.Write([]byte("{\"msg\":\"b\"}\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("{\"msg\":\"c\"}\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"2026/01/01.ndjson.gz": "{\"msg\":\"left\"}\n",
+		"2026/01/02.ndjson.gz": "{\"msg\":\"a\"}\n",
+		"2026/01/03.ndjson.gz": "{\"msg\":\"b\"}\n{\"msg\":\"c\"}\n",
+	} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(zr)
+		f.Close()
+		if string(got) != want {
+			t.Fatalf("%s: %q, want %q", name, got, want)
+		}
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "2026/01/*.ndjson")); len(left) != 0 {
+		t.Fatalf("left uncompressed: %v", left)
+	}
+}
+
+func TestCrashBundle(t *testing.T) {
+	SetWriter(io.Discard)
+	dir := t.TempDir()
+	SetCrashDir(dir, 2)
+	defer SetCrashDir("", 0)
+
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	Info().Msg("one")
+	Info().Msg("two")
+	Info().Msg("three")
+	Critical().Msg("down")
+	Critical().Msg("still down")
+
+	bundles, _ := filepath.Glob(filepath.Join(dir, "crash-*"))
+	if len(bundles) != 1 || strings.Count(buf.String(), `"crash_bundle":"`+bundles[0]+`"`) != 1 {
+		t.Fatalf("bundle %v not logged once: %s", bundles, buf.String())
+	}
+	records, _ := os.ReadFile(filepath.Join(bundles[0], "records.ndjson"))
+	if strings.Contains(string(records), "two") || !strings.Contains(string(records), "three") || !strings.Contains(string(records), `"msg":"down"`) {
+		t.Fatalf("unexpected tail: %s", records)
+	}
+	if runtime.GOOS != "windows" {
+		if fi, err := os.Stat(filepath.Join(bundles[0], "environ.txt")); err != nil || fi.Mode().Perm() != 0o600 {
+			t.Fatalf("bundle readable by others: %v %v", fi, err)
+		}
+	}
+}
+
+func TestNotifyOnCritical(t *testing.T) {
+	SetWriter(io.Discard)
+	addr := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", addr)
+	SetNotifyOnCritical(true, true)
+	defer SetNotifyOnCritical(false, false)
+
+	Error().Msg("not reported")
+	Critical().Msg("disk gone")
+
+	b := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b[:n]); got != "STATUS=disk gone\nWATCHDOG=trigger\n" {
+		t.Fatalf("unexpected state %q", got)
+	}
+}
+
+func TestDockerWriter(t *testing.T) {
+	var buf bytes.Buffer
+	setWriter(&dockerWriter{w: &buf, stream: "stdout"})
+	defer SetWriter(io.Discard)
+
+	Info().Msg("contained")
+	var line struct{ Log, Stream, Time string }
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Stream != "stdout" || !strings.HasSuffix(line.Log, "\"msg\":\"contained\"}\n") || line.Time == "" {
+		t.Fatalf("unexpected line: %+v", line)
+	}
+}
+
+func TestCRIWriter(t *testing.T) {
+	var buf bytes.Buffer
+	setWriter(&criWriter{w: &buf, stream: "stdout"})
+	defer SetWriter(io.Discard)
+
+	Info().Msg("contained")
+	ts, rest, _ := strings.Cut(buf.String(), " ")
+	if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rest, `stdout F {"ts":`) || !strings.HasSuffix(rest, "}\n") {
+		t.Fatalf("unexpected line: %q", buf.String())
+	}
+}
+
+func TestCombinedLog(t *testing.T) {
+	var buf bytes.Buffer
+	h := CombinedLog(&buf, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "short and stout")
+	}))
+	r := httptest.NewRequest("GET", "/pot?x=1", nil)
+	r.Header.Set("User-Agent", "curl/8")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := `"GET /pot?x=1 HTTP/1.1" 418 15 "-" "curl/8"` + "\n"
+	if !strings.HasPrefix(buf.String(), "192.0.2.1 - - [") || !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("unexpected line: %q", buf.String())
+	}
+
+	buf.Reset()
+	h = CombinedLog(&buf, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		if _, _, err := w.(http.Hijacker).Hijack(); err != http.ErrNotSupported {
+			t.Errorf("hijack: %v", err)
+		}
+	}))
+	r.Header.Set("User-Agent", "a \"b\"\\ \tü")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if !rec.Flushed {
+		t.Fatal("flush not passed through")
+	}
+	if want := ` 200 - "-" "a \"b\"\\ \t\xc3\xbc"` + "\n"; !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("unexpected line: %q", buf.String())
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	SetOutputs(
+		Output{Writer: phuslog.IOWriter{Writer: io.Discard}},
+		Output{Writer: &ArchiveWriter{Dir: dir}},
+	)
+	defer SetWriter(io.Discard)
+
+	results := HealthCheck(context.Background())
+	if err, ok := results["1 *log.ArchiveWriter"]; !ok || err != nil {
+		t.Fatalf("unexpected results: %v", results)
+	}
+
+	os.WriteFile(dir+"-file", nil, 0o644)
+	SetOutputs(Output{Writer: &ArchiveWriter{Dir: dir + "-file"}})
+	if err := HealthCheck(context.Background())["0 *log.ArchiveWriter"]; err == nil {
+		t.Fatal("expected unwritable archive to fail")
+	}
+
+	SetOutputs(Output{Writer: &FileWriter{Path: filepath.Join(dir+"-file", "app.log")}})
+	if err := HealthCheck(context.Background())["0 *log.FileWriter"]; err == nil {
+		t.Fatal("expected unwritable file to fail")
+	}
+
+	ro, err := os.Open(dir + "-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	SetOutputs(Output{Writer: phuslog.IOWriter{Writer: ro}})
+	if err := HealthCheck(context.Background())["0 log.IOWriter"]; err == nil {
+		t.Fatal("expected read-only file to fail")
+	}
+}
+
+func TestSelfLog(t *testing.T) {
+	var buf bytes.Buffer
+	SetSelfLog(&buf, phuslog.WarnLevel)
+	defer SetSelfLog(os.Stderr, phuslog.WarnLevel)
+
+	selfLog(phuslog.InfoLevel, "hidden", nil)
+	selfLog(phuslog.ErrorLevel, "shown", errors.New("boom"))
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), `"logger":"self","error":"boom","msg":"shown"`) {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	SetLevel(phuslog.InfoLevel)
+	defer SetLevel(phuslog.TraceLevel)
+
+	if DebugEnabled() || !InfoEnabled() {
+		t.Fatal("unexpected enabled levels")
+	}
+	Trace().Msg("trace")
+	Debugf("debug %d", 1)
+	Dump("dump", 1)
+	slog.Debug("slog debug")
+	Retry(context.Background(), "op", 1, errors.New("x"), time.Second, 0)
+	Info().Msg("info")
+	if got := strings.TrimSpace(buf.String()); strings.Count(got, "\n") != 1 || !strings.Contains(got, `"msg":"info"`) {
+		t.Fatalf("unexpected output: %s", got)
+	}
+}
+
+func TestTimer(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	timer := NewTimer("query", time.Hour)
+	for i := 1; i <= 100; i++ {
+		timer.Observe(time.Duration(i) * time.Millisecond)
+	}
+	timer.Stop()
+	timer.Stop()
+	for _, s := range []string{`"timer.count":100`, `"timer.p50":50`, `"timer.p95":95`, `"timer.max":100`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("missing %s: %s", s, buf.String())
+		}
+	}
+
+	// Nearest rank of 1..10: p50 is the 5th sample, p95 the 10th.
+	samples := make([]time.Duration, 10)
+	for i := range samples {
+		samples[i] = time.Duration(i + 1)
+	}
+	if p50, p95 := percentile(samples, 50), percentile(samples, 95); p50 != 5 || p95 != 10 {
+		t.Fatalf("p50 %d, p95 %d", p50, p95)
+	}
+	if p := percentile(samples[:1], 50); p != 1 {
+		t.Fatalf("single sample p50 %d", p)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	Count("orders", 2, Any("region", "eu"))
+	Gauge("queue.depth", 1.5)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"metric.name":"orders","metric.type":"counter","metric.value":2,"region":"eu"`) {
+		t.Fatalf("unexpected counter: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"metric.type":"gauge","metric.value":1.5`) {
+		t.Fatalf("unexpected gauge: %s", lines[1])
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	p := Progress("migrate", 100)
+	for range 100 {
+		p.Add(1)
+	}
+	p.Add(1)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("got %d records, want 10: %s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[9], `"msg":"migrate done"`) || !strings.Contains(lines[0], `"progress.eta":`) {
+		t.Fatalf("unexpected records: %s", buf.String())
+	}
+
+	buf.Reset()
+	defer func(d time.Duration) { ProgressInterval = d }(ProgressInterval)
+	ProgressInterval = 0
+	Progress("scan", 0).Add(5)
+	if got := buf.String(); !strings.Contains(got, `"progress.done":5,"progress.rate":`) || !strings.Contains(got, `"msg":"scan in progress"`) {
+		t.Fatalf("unknown total: %s", got)
+	}
+}
+
+type failCloser struct{}
+
+func (failCloser) Close() error { return errors.New("busy") }
+
+func TestIfErr(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	IfErr(nil, "quiet")
+	IfErr(errors.New("bad"), "loud", Any("id", 1))
+	CloseAndLog(failCloser{}, "closing")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d records, want 2: %s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], `"error":"bad","id":1,"msg":"loud"`) || !strings.Contains(lines[1], `"func":"log.TestIfErr"`) {
+		t.Fatalf("unexpected records: %s", buf.String())
+	}
+}
+
+func TestJoinedErrors(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	err := errors.Join(errors.New("a"), errors.Join(errors.New("b"), errors.New("c")))
+	Err(err).Msg("entry")
+	slog.Error("slog", "errs", err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"errors.0":"a","errors.1":"b","errors.2":"c"`) {
+		t.Fatalf("unexpected entry: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"errs":{"0":"a","1":"b","2":"c"}`) {
+		t.Fatalf("unexpected slog record: %s", lines[1])
+	}
+}
+
+var errQuota = errors.New("quota exceeded")
+
+func TestClassifier(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	classifiers := slices.Clone(_classifiers)
+	t.Cleanup(func() { _classifiers = classifiers })
+
+	errCorrupt := errors.New("corrupt")
+	RegisterClassifier(func(err error) (string, phuslog.Level) {
+		switch {
+		case errors.Is(err, errQuota):
+			return "quota", phuslog.WarnLevel
+		case errors.Is(err, errCorrupt):
+			return "corrupt", phuslog.PanicLevel
+		}
+		return "", 0
+	})
+	Err(fmt.Errorf("wrapped: %w", context.Canceled)).Msg("canceled")
+	Err(errQuota).Msg("quota")
+	Err(errors.New("other")).Msg("other")
+	Err(errCorrupt).Msg("corrupt")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{
+		`"level":"DEBG","error.class":"canceled"`,
+		`"level":"NOTI","error.class":"quota"`,
+		`"level":"ERRO","src":`,
+		`"level":"FATL","src":`,
+	} {
+		if !strings.Contains(lines[i], want) {
+			t.Fatalf("record %d missing %s: %s", i, want, lines[i])
+		}
+	}
+	if !strings.Contains(lines[3], `"error.class":"corrupt"`) {
+		t.Fatalf("record 3 not classified: %s", lines[3])
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var buf syncBuffer
+	SetWriter(&buf)
+
+	started := make(chan struct{})
+	done := make(chan struct{})
+	id := Batch(func() {
+		Info().Msg("config a")
+		go func() {
+			close(started)
+			Info().Msg("other")
+			close(done)
+		}()
+		<-started
+		time.Sleep(10 * time.Millisecond)
+		Info().Msg("config b")
+	})
+	<-done
+	Info().Msg("after")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d records: %s", len(lines), buf.String())
+	}
+	for i, msg := range []string{"config a", "config b", "other", "after"} {
+		if !strings.Contains(lines[i], `"msg":"`+msg+`"`) {
+			t.Fatalf("record %d is not %q: %s", i, msg, lines[i])
+		}
+		batched := strings.Contains(lines[i], `"batch_id":"`+id+`"`)
+		if batched != (i < 2) {
+			t.Fatalf("record %d batch_id: %s", i, lines[i])
+		}
+	}
+}
+
+func TestScope(t *testing.T) {
+	var buf bytes.Buffer
+	setWriter(NewConsoleWriter(&buf))
+	defer SetWriter(io.Discard)
+
+	end := Scope("loading plugins")
+	Info().Msg("auth")
+	inner := Scope("cache")
+	Info().Str("scope", "read").Msg("redis")
+	inner()
+	end()
+	Info().Msg("ready")
+
+	var got []string
+	for line := range strings.Lines(buf.String()) {
+		_, rest, _ := strings.Cut(line, " ")
+		got = append(got, rest)
+	}
+	want := []string{
+		"level=INFO \"loading plugins\"\n",
+		"level=INFO   \"auth\"\n",
+		"level=INFO   \"cache\"\n",
+		"level=INFO     scope=\"read\" \"redis\"\n",
+		"level=INFO \"ready\"\n",
+	}
+	if strings.Join(got, "") != strings.Join(want, "") {
+		t.Fatalf("got:\n%s", strings.Join(got, ""))
+	}
+
+	var js bytes.Buffer
+	SetWriter(&js)
+	defer Scope("loading plugins")()
+	Info().Msg("auth")
+	if !strings.Contains(js.String(), `"log.scope":"loading plugins"`) {
+		t.Fatalf("scope field missing: %s", js.String())
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type config struct {
+		Port    int               `json:"port"`
+		Timeout int               `json:"timeout,omitempty"`
+		Plugins map[string]bool   `json:"plugins"`
+		Labels  map[string]string `json:"labels,omitempty"`
+	}
+	old := config{Port: 80, Timeout: 5, Plugins: map[string]bool{"auth": true}}
+	new := config{Port: 8080, Plugins: map[string]bool{"auth": true, "cache": true}}
+
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	if Diff("config changed", old, old) || buf.Len() != 0 {
+		t.Fatalf("equal values logged: %s", buf.String())
+	}
+	if !Diff("config changed", old, new) {
+		t.Fatal("no difference reported")
+	}
+	var got struct {
+		Added   map[string]any `json:"diff.added"`
+		Removed map[string]any `json:"diff.removed"`
+		Changed map[string]any `json:"diff.changed"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	if got.Added["plugins.cache"] != true || got.Removed["timeout"] != 5.0 ||
+		got.Changed["port"].(map[string]any)["new"] != 8080.0 {
+		t.Fatalf("diff: %s", buf.String())
+	}
+
+	buf.Reset()
+	setWriter(&phuslog.MultiEntryWriter{NewConsoleWriter(&buf)})
+	defer SetWriter(io.Discard)
+	Diff("config changed", old, new)
+	want := "  diff:\n    + plugins.cache: true\n    - timeout: 5\n    ~ port: 80 -> 8080\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("console:\n%s", buf.String())
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	Msgk(Info(), "loaded %d plugins", 3)
+	SetCatalog(map[string]string{"loaded %d plugins": "%d Plugins geladen"})
+	defer SetCatalog(nil)
+	Msgk(Info(), "loaded %d plugins", 3)
+	Msgk(Info(), "ready")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{
+		`"msg":"loaded 3 plugins"}`,
+		`"msg_en":"loaded 3 plugins","msg":"3 Plugins geladen"}`,
+		`"msg":"ready"}`,
+	} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Fatalf("record %d: %s", i, lines[i])
+		}
+	}
+	if got := T("loaded %d plugins", 1); got != "1 Plugins geladen" {
+		t.Fatalf("T: %q", got)
+	}
+}
+
+func TestClassified(t *testing.T) {
+	var local, saas bytes.Buffer
+	SetOutputs(
+		Output{Writer: phuslog.IOWriter{Writer: &local}},
+		Output{Writer: phuslog.IOWriter{Writer: &saas}, MaxClass: Internal},
+	)
+	defer SetWriter(io.Discard)
+	ch := make(chan Record, 8)
+	defer SubscribeUpTo(ch, Internal)()
+
+	Info().Msg("started")
+	Info().Func(Classified(Internal)).Msg("usage")
+	Info().Func(Classified(Confidential)).Str("ssn", "078-05-1120").Msg("verified")
+	Info().Str("note", `"classification":"confidential"`).Dict("user", phuslog.NewContext(nil).Str("classification", "confidential").Value()).Msg("mentions")
+
+	if n := strings.Count(local.String(), "\n"); n != 4 {
+		t.Fatalf("local got %d records: %s", n, local.String())
+	}
+	if n := strings.Count(saas.String(), "\n"); n != 3 || strings.Contains(saas.String(), "ssn") {
+		t.Fatalf("saas got %d records: %s", n, saas.String())
+	}
+	if n := len(ch); n != 3 {
+		t.Fatalf("subscriber got %d records", n)
+	}
+}
+
+func TestErase(t *testing.T) {
+	dir := t.TempDir()
+	records := `{"user_id":"42","msg":"login"}
+{"user_id":"7","msg":"login"}
+{"user_id":42,"msg":"logout"}
+`
+	plain := filepath.Join(dir, "01.ndjson")
+	if err := os.WriteFile(plain, []byte(records), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "02.ndjson"), []byte(records), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipFile(filepath.Join(dir, "02.ndjson")); err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := EraseDir(dir, "user_id", "42", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range reports {
+		if r.Records != 3 || r.Erased != 2 || !r.Verified {
+			t.Fatalf("report: %+v", r)
+		}
+	}
+	data, _ := os.ReadFile(plain)
+	if string(data) != `{"user_id":"7","msg":"login"}`+"\n" {
+		t.Fatalf("erased file: %s", data)
+	}
+
+	os.WriteFile(plain, []byte(records), 0o600)
+	if _, err := Erase(plain, "user_id", "42", true); err == nil {
+		t.Fatal("hashed without a keyed hash")
+	}
+	key := []byte("secret")
+	SetHasher(func() hash.Hash { return hmac.New(sha256.New, key) })
+	defer SetHasher(nil)
+	r, err := Erase(plain, "user_id", "42", true)
+	if err != nil || r.Erased != 2 || !r.Verified {
+		t.Fatalf("hash: %+v %v", r, err)
+	}
+	data, _ = os.ReadFile(plain)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("42"))
+	digest := hex.EncodeToString(mac.Sum(nil))
+	if strings.Count(string(data), `{"user_id":"`+digest+`"`) != 2 {
+		t.Fatalf("hashed file: %s", data)
+	}
+}
+
+func TestSetHasher(t *testing.T) {
+	SetHasher(sha512.New)
+	defer SetHasher(nil)
+	if got := hashValue("42"); len(got) != 128 {
+		t.Fatalf("digest: %s", got)
+	}
+}
+
+func TestFileWriterShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	record := []byte(`{"msg":"` + strings.Repeat("x", 8192) + `"}` + "\n")
+
+	var wg sync.WaitGroup
+	for range 4 {
+		w := &FileWriter{Path: path, Lock: true}
+		wg.Go(func() {
+			defer w.Close()
+			for range 50 {
+				if _, err := w.Write(record); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 4*50*len(record) || strings.Count(string(data), string(record)) != 4*50 {
+		t.Fatalf("records interleaved: %d bytes", len(data))
+	}
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w := &FileWriter{Path: path, ReopenCheck: time.Nanosecond}
+	defer w.Close()
+
+	w.Write([]byte("{\"n\":1}\n"))
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	w.Write([]byte("{\"n\":2}\n"))
+
+	rotated, _ := os.ReadFile(path + ".1")
+	current, _ := os.ReadFile(path)
+	if string(rotated) != "{\"n\":1}\n" || string(current) != "{\"n\":2}\n" {
+		t.Fatalf("rotated %q, current %q", rotated, current)
+	}
+
+	w.Close()
+	os.Remove(path)
+	if _, err := w.Write([]byte("{\"n\":3}\n")); err == nil {
+		t.Fatal("write after Close succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file reopened after Close: %v", err)
+	}
+}
+
+func TestCompressedWriter(t *testing.T) {
+	var buf syncBuffer
+	w := &CompressedWriter{Compressor: gzip.NewWriter(&buf), FlushInterval: time.Millisecond}
+	w.Write([]byte("{\"msg\":\"deep\"}\n"))
+	time.Sleep(50 * time.Millisecond)
+
+	// The flushed stream decompresses up to the last record before it
+	// is closed.
+	zr, err := gzip.NewReader(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(zr)
+	if string(got) != "{\"msg\":\"deep\"}\n" {
+		t.Fatalf("flushed: %q", got)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTraceBurst(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	SetLevel(phuslog.InfoLevel)
+	defer SetLevel(phuslog.TraceLevel)
+
+	TraceNext(2)
+	Trace().Msg("one")
+	Trace().Msg("two")
+	Trace().Msg("dropped")
+	if got := strings.Count(buf.String(), "\n"); got != 2 || TraceEnabled() {
+		t.Fatalf("TraceNext wrote %d records: %s", got, buf.String())
+	}
+
+	TraceFor(10 * time.Millisecond)
+	if !TraceEnabled() {
+		t.Fatal("TraceFor did not enable trace")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if TraceEnabled() || !InfoEnabled() {
+		t.Fatal("TraceFor did not restore the level")
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	SetLevel(phuslog.InfoLevel)
+	defer SetLevel(phuslog.TraceLevel)
+
+	srv := httptest.NewServer(TriggerHandler())
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"?key=user_id&value=12345", "", nil)
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatal(resp, err)
+	}
+
+	Bind(func(e *phuslog.Entry) { e.Int("user_id", 123456) })
+	Trace().Msg("other user")
+	Unbind()
+	Bind(func(e *phuslog.Entry) { e.Int("user_id", 12345) })
+	Trace().Msg("traced")
+	slog.Debug("slog traced")
+	Unbind()
+
+	Untrigger("user_id", "12345")
+	Bind(func(e *phuslog.Entry) { e.Int("user_id", 12345) })
+	Trace().Msg("untriggered")
+	Unbind()
+
+	got := buf.String()
+	if strings.Count(got, "\n") != 2 || !strings.Contains(got, `"msg":"traced"`) || !strings.Contains(got, `"msg":"slog traced"`) {
+		t.Fatalf("unexpected output: %s", got)
+	}
+}
+
+func TestGoroutineReport(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	if GoroutineReport(1 << 20) {
+		t.Fatal("reported under threshold")
+	}
+	stop := make(chan struct{})
+	for range 50 {
+		go func() { <-stop }()
+	}
+	defer close(stop)
+	if !GoroutineReport(10) {
+		t.Fatal("not reported over threshold")
+	}
+	var rec struct {
+		Sites string `json:"goroutines.sites"`
+	}
+	json.Unmarshal(buf.Bytes(), &rec)
+	first, _, _ := strings.Cut(rec.Sites, "\n")
+	if !strings.HasPrefix(first, "     50 github.com/xtdlib/log.TestGoroutineReport at ") {
+		t.Fatalf("unexpected report:\n%s", rec.Sites)
+	}
+}
+
+var memoryBallast []byte
+
+func TestWatchMemory(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	// Collect the ballast of earlier runs so the baseline is small.
+	memoryBallast = nil
+	runtime.GC()
+	w := &memoryWatch{limits: MemoryThresholds{HeapGrowth: 0.5, GCPause: time.Hour}}
+	w.sample()
+	memoryBallast = make([]byte, 256<<20)
+	defer func() { memoryBallast = nil }()
+	w.sample()
+	w.sample()
+
+	got := buf.String()
+	if strings.Count(got, "\n") != 1 || !strings.Contains(got, `"mem.event":"heap_growth"`) {
+		t.Fatalf("unexpected records: %s", got)
+	}
+}
+
+func TestFileWriterMinFree(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := freeSpace(dir); !ok {
+		t.Skip("free space not available")
+	}
+	var self bytes.Buffer
+	SetSelfLog(&self, phuslog.WarnLevel)
+	defer SetSelfLog(os.Stderr, phuslog.WarnLevel)
+
+	w := &FileWriter{Path: filepath.Join(dir, "app.log"), MinFree: 1 << 62}
+	defer w.Close()
+	if _, err := w.Write([]byte("{}\n")); err != errDegraded {
+		t.Fatalf("write on a full disk: %v", err)
+	}
+	if !strings.Contains(self.String(), `"level":"ERRO"`) || !strings.Contains(self.String(), "writes stopped") {
+		t.Fatalf("no self log record: %s", self.String())
+	}
+}
+
+func TestInit(t *testing.T) {
+	var buf bytes.Buffer
+	Init(WithWriter(&buf), WithLevel(phuslog.InfoLevel))
+	defer Init(WithWriter(io.Discard), WithLevel(phuslog.TraceLevel))
+
+	slog.Debug("hidden")
+	slog.Info("shown")
+	Debug().Msg("hidden")
+	if got := buf.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, `"msg":"shown"`) {
+		t.Fatalf("unexpected output: %s", got)
+	}
+}
+
+func TestAdapters(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	RedisLogger{}.Printf(context.Background(), "redis: discarding bad conn: %v", io.ErrUnexpectedEOF)
+	EntLog("driver.Query: query=SELECT 1 args=[]")
+	Query(context.Background(), time.Now(), "SELECT * FROM users", 0, context.Canceled)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{
+		`"level":"NOTI","component":"redis","msg":"redis: discarding bad conn: unexpected EOF"`,
+		`"level":"DEBG","component":"ent","msg":"driver.Query: query=SELECT 1 args=[]"`,
+		`"level":"DEBG","db.statement":"SELECT * FROM users","db.rows":0,`,
+	} {
+		if !strings.Contains(lines[i], want) {
+			t.Fatalf("record %d: %s", i, lines[i])
+		}
+	}
+	if !strings.Contains(lines[2], `"error.class":"canceled","error":"context canceled"`) {
+		t.Fatalf("query error: %s", lines[2])
+	}
+}
+
+func TestCode(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+
+	Error().Func(Code("AUTH_401")).Msg("unchecked")
+	RegisterCodes("AUTH_401")
+	Error().Func(Code("AUTH_401")).Msg("registered")
+	Error().Func(Code("AUTH_999")).Msg("unregistered")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"error.code":"AUTH_401","msg"`) || !strings.Contains(lines[1], `"error.code":"AUTH_401","msg"`) {
+		t.Fatalf("unexpected records: %s", buf.String())
+	}
+	if !strings.Contains(lines[2], `"error.code":"AUTH_999","!BADCODE":"unregistered code AUTH_999"`) {
+		t.Fatalf("unregistered code not reported: %s", lines[2])
+	}
+}
+
+func TestSetResource(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	defer SetResource()
+
+	SetResource(slog.String("service.name", "billing"), slog.Group("deployment", slog.String("environment", "prod")))
+	Info().Msg("native")
+	slog.Info("slog")
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.Contains(line, `"level":"INFO","service.name":"billing","deployment.environment":"prod"`) {
+			t.Fatalf("missing resource: %s", line)
+		}
+	}
+}
+
+func TestSetAppName(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	defer SetHostname("")
+	defer SetAppName("")
+
+	SetAppName("billing")
+	SetHostname("web-1")
+	Info().Msg("named")
+	if err := DetectHostname(); err != nil {
+		t.Fatal(err)
+	}
+	host, _ := os.Hostname()
+	Info().Msg("detected")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"app":"billing","host":"web-1"`) || !strings.Contains(lines[1], `"host":"`+host+`"`) {
+		t.Fatalf("unexpected records: %s", buf.String())
+	}
+
+	msg, _, err := (&JournalWriter{}).fields([]byte(`{"msg":"named"}`))
+	if err != nil || !strings.Contains(string(msg), "SYSLOG_IDENTIFIER=billing\n") {
+		t.Fatalf("journal identifier is not the app name: %q %v", msg, err)
+	}
+}
+
+func TestQuickwitWriter(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, r.Header.Get("Authorization")+" "+r.URL.String()+" "+string(body))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	w := &QuickwitWriter{
+		URL: srv.URL, Index: "app", Commit: "force", BatchSize: 2,
+		Header: http.Header{"Authorization": {"Bearer t0k"}},
+	}
+	for i := range 3 {
+		fmt.Fprintf(w, "{\"n\":%d}\n", i)
+	}
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Bearer t0k /api/v1/app/ingest?commit=force {\"n\":0}\n{\"n\":1}\n",
+		"Bearer t0k /api/v1/app/ingest?commit=force {\"n\":2}\n",
+	}
+	if strings.Join(requests, "|") != strings.Join(want, "|") {
+		t.Fatalf("requests: %q", requests)
+	}
+}
+
+func TestShipperRetries(t *testing.T) {
+	defer func(d time.Duration) { shipBackoff = d }(shipBackoff)
+	shipBackoff = time.Millisecond
+	var self syncBuffer
+	SetSelfLog(&self, phuslog.WarnLevel)
+	defer SetSelfLog(os.Stderr, phuslog.WarnLevel)
+
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "index not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	w := &QuickwitWriter{URL: srv.URL, Index: "missing"}
+	w.Write([]byte("{}\n"))
+	w.Close()
+	if attempts != 1+shipRetries || !strings.Contains(self.String(), `"error":"1 records: 404 Not Found: index not found"`) {
+		t.Fatalf("%d attempts, self log: %s", attempts, self.String())
+	}
+	if _, err := w.Write([]byte("{}\n")); err != errShipperClosed {
+		t.Fatalf("write after close: %v", err)
+	}
+}
+
+func TestShipperCloseTimeout(t *testing.T) {
+	defer func(d time.Duration) { shipCloseTimeout = d }(shipCloseTimeout)
+	shipCloseTimeout = 50 * time.Millisecond
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	w := &QuickwitWriter{URL: srv.URL, Index: "app"}
+	w.Write([]byte("{}\n"))
+	if err := w.Close(); err != errShipperTimeout {
+		t.Fatalf("close of hung writer: %v", err)
+	}
+}
+
+func TestJournalWriter(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	w := &JournalWriter{Socket: socket, Identifier: "billing", Level: phuslog.InfoLevel}
+	defer w.Close()
+	setWriter(w)
+	defer SetWriter(io.Discard)
+	Debug().Msg("below level")
+	w.Write([]byte(`{"level":"DEBG","msg":"written below level"}` + "\n"))
+	Error().Str("request-id", "abc").Str("detail", "a\nb").Str("message", "user").Msg("failed")
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	for _, want := range []string{
+		"SYSLOG_IDENTIFIER=billing\n",
+		"PRIORITY=3\n",
+		"log_test.go\nCODE_LINE=",
+		"CODE_FUNC=log.TestJournalWriter\n",
+		"REQUEST_ID=abc\n",
+		"DETAIL\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n",
+		"\nMESSAGE=failed\n",
+		"F_MESSAGE=user\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("missing %q in %q", want, got)
+		}
+	}
+
+	Info().Str("body", strings.Repeat("x", 1<<20)).Msg("large")
+	oob := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil || n != 0 || oobn == 0 {
+		t.Fatalf("large record not sent as a descriptor: %d %d %v", n, oobn, err)
+	}
+
+	// A restarted journal is dialed again after the failed write.
+	conn.Close()
+	os.Remove(socket)
+	conn, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := w.Write([]byte(`{"level":"INFO","msg":"lost"}`)); err == nil {
+		t.Fatal("write to the old journal succeeded")
+	}
+	if _, err := w.Write([]byte(`{"level":"INFO","msg":"redialed"}`)); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := conn.Read(buf); err != nil || !strings.Contains(string(buf[:n]), "MESSAGE=redialed\n") {
+		t.Fatalf("not redialed: %q %v", buf[:n], err)
+	}
+}
+
+func TestOpenObserveWriter(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		got = r.URL.Path + " " + user + ":" + pass + " " + string(body)
+	}))
+	defer srv.Close()
+
+	w := &OpenObserveWriter{URL: srv.URL, Org: "default", Stream: "app", User: "root", Password: "secret"}
+	w.Write([]byte("{\"n\":0}\n"))
+	w.Write([]byte("{\"n\":1}\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `/api/default/app/_json root:secret [{"n":0},{"n":1}]`; got != want {
+		t.Fatalf("request: %s", got)
+	}
+}
+
+func TestWithCaller(t *testing.T) {
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	WithCaller(2)
+	defer WithCaller(0)
+
+	Info().Msg("here")
+	if !strings.Contains(buf.String(), `"func":"log.TestWithCaller"`) {
+		t.Fatalf("caller is not the test: %s", buf.String())
+	}
+}
+
+func TestLazyInit(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "LOG_TEST_FIRST_RECORD=1", "LOG_FORMAT=json")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), `{"ts":`) || !strings.Contains(string(out), `"level":"INFO"`) {
+		t.Fatalf("first record not set up: %s", out)
+	}
+}
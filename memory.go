@@ -0,0 +1,111 @@
+package log
+
+import (
+	"math"
+	"runtime/metrics"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// MemoryThresholds are the limits WatchMemory warns about. Zero limits are
+// not checked.
+type MemoryThresholds struct {
+	// GCPause is the longest acceptable stop-the-world GC pause.
+	GCPause time.Duration
+	// HeapGrowth is the largest acceptable growth of the heap between two
+	// samples, as a fraction: 0.5 warns when it grew by more than half.
+	HeapGrowth float64
+}
+
+// WatchMemory samples the runtime metrics every period and logs a record
+// at notice level whenever a GC pause or the heap growth since the last
+// sample exceeds its threshold, so memory incidents show up among the
+// application's records. The records carry mem.event, either gc_pause or
+// heap_growth, and the measurements under mem. The returned function stops
+// the watch.
+func WatchMemory(period time.Duration, limits MemoryThresholds) (stop func()) {
+	w := &memoryWatch{limits: limits}
+	w.sample()
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(period)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				w.sample()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+type memoryWatch struct {
+	limits MemoryThresholds
+	pauses []uint64
+	heap   uint64
+	// started is set once a first sample has been taken.
+	started bool
+}
+
+var memorySamples = []metrics.Sample{
+	{Name: "/sched/pauses/total/gc:seconds"},
+	{Name: "/memory/classes/heap/objects:bytes"},
+}
+
+// sample reads the metrics and logs the thresholds exceeded since the last
+// sample.
+func (w *memoryWatch) sample() {
+	s := make([]metrics.Sample, len(memorySamples))
+	copy(s, memorySamples)
+	metrics.Read(s)
+
+	var pause time.Duration
+	if s[0].Value.Kind() == metrics.KindFloat64Histogram {
+		h := s[0].Value.Float64Histogram()
+		for i := len(h.Counts) - 1; i >= 0; i-- {
+			var prev uint64
+			if i < len(w.pauses) {
+				prev = w.pauses[i]
+			}
+			if h.Counts[i] > prev {
+				// The upper bound of the bucket, or its lower bound for
+				// the last, unbounded one.
+				upper := h.Buckets[i+1]
+				if math.IsInf(upper, 1) {
+					upper = h.Buckets[i]
+				}
+				pause = time.Duration(upper * float64(time.Second))
+				break
+			}
+		}
+		w.pauses = append(w.pauses[:0], h.Counts...)
+	}
+	var heap uint64
+	if s[1].Value.Kind() == metrics.KindUint64 {
+		heap = s[1].Value.Uint64()
+	}
+
+	if w.started {
+		if w.limits.GCPause > 0 && pause > w.limits.GCPause {
+			bind(phuslog.WarnLevel).
+				Str("mem.event", "gc_pause").
+				Dur("mem.gc_pause", pause).
+				Dur("mem.gc_pause_threshold", w.limits.GCPause).
+				Msg("gc pause over threshold")
+		}
+		growth := float64(heap)/float64(w.heap) - 1
+		if w.limits.HeapGrowth > 0 && w.heap > 0 && growth > w.limits.HeapGrowth {
+			bind(phuslog.WarnLevel).
+				Str("mem.event", "heap_growth").
+				Uint64("mem.heap_bytes", heap).
+				Float64("mem.heap_growth", growth).
+				Float64("mem.heap_growth_threshold", w.limits.HeapGrowth).
+				Msg("heap growth over threshold")
+		}
+	}
+	w.heap, w.started = heap, true
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	phuslog "github.com/phuslu/log"
+)
+
+// Count logs an increment of the counter name by delta, as an info record
+// with metric.name, metric.type "counter" and metric.value, so log stores
+// such as VictoriaLogs can compute the metric with stats queries.
+func Count(name string, delta int64, fields ...func(e *phuslog.Entry)) {
+	e := bind(phuslog.InfoLevel)
+	if e == nil {
+		return
+	}
+	e.Str("metric.name", name).Str("metric.type", "counter").Int64("metric.value", delta)
+	for _, f := range fields {
+		f(e)
+	}
+	e.Msg(name)
+}
+
+// Gauge logs the current value of the gauge name, like Count but with
+// metric.type "gauge".
+func Gauge(name string, value float64, fields ...func(e *phuslog.Entry)) {
+	e := bind(phuslog.InfoLevel)
+	if e == nil {
+		return
+	}
+	e.Str("metric.name", name).Str("metric.type", "gauge").Float64("metric.value", value)
+	for _, f := range fields {
+		f(e)
+	}
+	e.Msg(name)
+}
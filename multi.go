@@ -0,0 +1,54 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// NewMultiHandler returns a slog.Handler that fans records out to handlers.
+// Each record is passed only to the handlers enabled for its level, and the
+// errors they return are joined.
+func NewMultiHandler(handlers ...slog.Handler) slog.Handler {
+	return multiHandler(handlers)
+}
+
+type multiHandler []slog.Handler
+
+func (h multiHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, hh := range h {
+		if hh.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, hh := range h {
+		if !hh.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := hh.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hs := make(multiHandler, len(h))
+	for i, hh := range h {
+		hs[i] = hh.WithAttrs(attrs)
+	}
+	return hs
+}
+
+func (h multiHandler) WithGroup(name string) slog.Handler {
+	hs := make(multiHandler, len(h))
+	for i, hh := range h {
+		hs[i] = hh.WithGroup(name)
+	}
+	return hs
+}
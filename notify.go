@@ -0,0 +1,63 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+var (
+	_notify         atomic.Bool
+	_notifyWatchdog atomic.Bool
+)
+
+// SetNotifyOnCritical makes critical records set the systemd service
+// status to their message through sd_notify, so they show up in
+// `systemctl status`. With watchdog set, they also trigger the service
+// watchdog (WATCHDOG=trigger). It does nothing unless NOTIFY_SOCKET is set.
+func SetNotifyOnCritical(enable, watchdog bool) {
+	_notify.Store(enable)
+	_notifyWatchdog.Store(watchdog)
+}
+
+// notifyCritical reports record to systemd if it is a critical one.
+func notifyCritical(record []byte) {
+	if !_notify.Load() {
+		return
+	}
+	level := `"` + phuslog.LevelKey + `":"` + phuslog.FatalLevelString + `"`
+	if !bytes.Contains(record, []byte(level)) {
+		return
+	}
+	var fields map[string]any
+	json.Unmarshal(record, &fields)
+	msg, _ := fields[phuslog.MessageKey].(string)
+
+	state := "STATUS=" + strings.ReplaceAll(msg, "\n", " ") + "\n"
+	if _notifyWatchdog.Load() {
+		state += "WATCHDOG=trigger\n"
+	}
+	if err := sdNotify(state); err != nil {
+		selfLog(phuslog.WarnLevel, "systemd notification failed", err)
+	}
+}
+
+// sdNotify sends state to the systemd notification socket, if any.
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	conn, err := net.Dial("unixgram", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
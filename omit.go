@@ -0,0 +1,19 @@
+package log
+
+// Omit selects the empty values dropped from records as they are written.
+// The core fields every record carries are always kept.
+type Omit uint8
+
+const (
+	OmitEmptyString Omit = 1 << iota
+	OmitZeroNumber
+	OmitNil
+	OmitEmptyGroup
+)
+
+// SetOmit sets which empty values are dropped from the records written to
+// every output, to save storage on wide records; Output.Omit overrides it
+// for one output.
+func SetOmit(o Omit) {
+	setRewrite(func() { _omit = o })
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// OpenObserveWriter ships records in batches to the _json ingestion
+// endpoint of an OpenObserve stream, batching and retrying as
+// QuickwitWriter does:
+//
+//	oo := &log.OpenObserveWriter{
+//		URL: "https://openobserve:5080", Org: "default", Stream: "app",
+//		User: user, Password: password,
+//	}
+//	defer oo.Close()
+type OpenObserveWriter struct {
+	// URL is the base URL of the OpenObserve server.
+	URL string
+
+	// Org and Stream select the stream ingested into.
+	Org, Stream string
+
+	// User and Password authenticate the requests with basic auth, if
+	// User is set.
+	User, Password string
+
+	// BatchSize is the most records posted at once, 1000 if zero.
+	BatchSize int
+
+	// FlushInterval is how long a record may wait for its batch to fill
+	// up, one second if zero.
+	FlushInterval time.Duration
+
+	// Header is added to every request, e.g. for a bearer token or the
+	// headers an authenticating proxy expects.
+	Header http.Header
+
+	// Client is the client posting the batches, one with a 10 second
+	// timeout if nil.
+	Client *http.Client
+
+	once sync.Once
+	s    *shipper
+}
+
+// WriteEntry implements phuslog.Writer.
+func (w *OpenObserveWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	return w.Write(e.Value())
+}
+
+// Write queues p, a complete record.
+func (w *OpenObserveWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		w.s = newShipper(w.send, w.BatchSize, w.FlushInterval)
+		addCloser(w)
+	})
+	if w.s == nil {
+		return 0, errShipperClosed
+	}
+	return w.s.write(p)
+}
+
+// Close posts the queued records and stops the writer.
+func (w *OpenObserveWriter) Close() error {
+	w.once.Do(func() {})
+	if w.s == nil {
+		return nil
+	}
+	return w.s.close()
+}
+
+func (w *OpenObserveWriter) send(records [][]byte) error {
+	var body bytes.Buffer
+	body.WriteByte('[')
+	for i, r := range records {
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(bytes.TrimRight(r, "\n"))
+	}
+	body.WriteByte(']')
+	u := w.URL + "/api/" + url.PathEscape(w.Org) + "/" + url.PathEscape(w.Stream) + "/_json"
+	req, err := http.NewRequest(http.MethodPost, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Header {
+		req.Header[k] = v
+	}
+	if w.User != "" {
+		req.SetBasicAuth(w.User, w.Password)
+	}
+	return post(w.Client, req)
+}
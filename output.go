@@ -0,0 +1,143 @@
+package log
+
+import (
+	"bytes"
+	"slices"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// output wraps the configured writer to observe every record written
+// through _default, including those logged via slog.
+type output struct {
+	phuslog.Writer
+}
+
+// _records counts the records written through output.
+var _records atomic.Int64
+
+func (w *output) WriteEntry(e *phuslog.Entry) (int, error) {
+	defer waitBatch()()
+	if sampled(e) {
+		return 0, nil
+	}
+	publish(e.Value())
+	keepTail(e.Value())
+	writeCrashBundle(e)
+	notifyCritical(e.Value())
+	countBurst()
+	r := _rewrite.Load()
+	if _records.Add(1)%statsSampleEvery != 0 {
+		return writeEntry(w.Writer, e, r)
+	}
+	allocs := heapAllocs()
+	n, err := writeEntry(w.Writer, e, r)
+	_sampledAllocs.Add(heapAllocs() - allocs)
+	_sampledWrites.Add(1)
+	return n, err
+}
+
+// setWriter makes w the destination of all records.
+func setWriter(w phuslog.Writer) {
+	lazyInit()
+	_default.Writer = &output{w}
+	resetSlog()
+}
+
+// writer returns the configured writer.
+func writer() phuslog.Writer {
+	lazyInit()
+	if o, ok := _default.Writer.(*output); ok {
+		return o.Writer
+	}
+	return _default.Writer
+}
+
+// Output is a destination for the records at or above Level, critical
+// records counting as FatalLevel.
+type Output struct {
+	Writer phuslog.Writer
+	Level  phuslog.Level
+
+	// MaxClass is the most sensitive classification of records the output
+	// gets, for keeping confidential records off third-party services.
+	// Zero means all records.
+	MaxClass Classification
+
+	// FieldNames renames the core keys of the records the output gets, in
+	// place of those set by SetFieldNames.
+	FieldNames FieldNames
+
+	// Omit selects the empty values dropped from the records the output
+	// gets, in place of those set by SetOmit.
+	Omit Omit
+
+	rewrite *recordRewrite
+}
+
+// SetOutputs sends each record to every output whose level it reaches,
+// e.g. trace and debug only to the console while info and above also go to
+// a JSON file:
+//
+//	log.SetOutputs(
+//		log.Output{Writer: log.NewConsoleWriter(os.Stderr), Level: phuslog.TraceLevel},
+//		log.Output{Writer: phuslog.IOWriter{Writer: f}, Level: phuslog.InfoLevel},
+//	)
+func SetOutputs(outputs ...Output) {
+	outputs = slices.Clone(outputs)
+	for i, o := range outputs {
+		outputs[i].rewrite = newRewrite(o.FieldNames, o.Omit)
+	}
+	setWriter(levelOutputs(outputs))
+}
+
+type levelOutputs []Output
+
+func (w levelOutputs) WriteEntry(e *phuslog.Entry) (n int, err error) {
+	return w.write(e, _rewrite.Load())
+}
+
+// write sends e to the outputs it reaches, rewritten by their own rewrite
+// or else by r.
+func (w levelOutputs) write(e *phuslog.Entry, r *recordRewrite) (n int, err error) {
+	var class Classification
+	level := entryLevel(e)
+	for _, o := range w {
+		if level < o.Level {
+			continue
+		}
+		if o.MaxClass != 0 {
+			if class == 0 {
+				class = classification(e.Value())
+			}
+			if class > o.MaxClass {
+				continue
+			}
+		}
+		rewrite := r
+		if o.rewrite != nil {
+			rewrite = o.rewrite
+		}
+		if n1, err1 := writeEntry(o.Writer, e, rewrite); err1 != nil {
+			err = err1
+		} else {
+			n = n1
+		}
+	}
+	return
+}
+
+// entryLevel returns the level of e, taking critical records, which carry
+// ErrorLevel, as FatalLevel.
+func entryLevel(e *phuslog.Entry) phuslog.Level {
+	if e.Level != phuslog.ErrorLevel {
+		return e.Level
+	}
+	b := e.Value()
+	key := `"` + phuslog.LevelKey + `":"`
+	if i := bytes.Index(b, []byte(key)); i >= 0 && bytes.HasPrefix(b[i+len(key):], []byte("FATL")) {
+		return phuslog.FatalLevel
+	}
+	return e.Level
+}
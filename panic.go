@@ -0,0 +1,64 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// panicValue returns the fields describing a recovered value v: its text
+// in panic and its type in panic.type, so panics can be grouped by type.
+// Errors also carry the errors they wrap in panic.wrapped, structs their
+// JSON encoding in panic.value.
+func panicValue(v any) func(e *phuslog.Entry) {
+	return func(e *phuslog.Entry) {
+		e.Str("panic", fmt.Sprint(v)).Str("panic.type", fmt.Sprintf("%T", v))
+		if err, ok := v.(error); ok {
+			if wrapped := unwrapAll(err); len(wrapped) != 0 {
+				b, _ := json.Marshal(wrapped)
+				e.RawJSON("panic.wrapped", b)
+			}
+			return
+		}
+		t := reflect.TypeOf(v)
+		if t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return
+		}
+		if b, err := json.Marshal(v); err == nil {
+			e.RawJSON("panic.value", b)
+		}
+	}
+}
+
+type wrappedError struct {
+	Type string `json:"type"`
+	Msg  string `json:"msg"`
+}
+
+// unwrapAll returns the errors err wraps, depth first.
+func unwrapAll(err error) []wrappedError {
+	var all []wrappedError
+	var walk func(err error)
+	walk = func(err error) {
+		var errs []error
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			if e := u.Unwrap(); e != nil {
+				errs = []error{e}
+			}
+		case interface{ Unwrap() []error }:
+			errs = u.Unwrap()
+		}
+		for _, e := range errs {
+			all = append(all, wrappedError{fmt.Sprintf("%T", e), e.Error()})
+			walk(e)
+		}
+	}
+	walk(err)
+	return all
+}
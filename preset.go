@@ -0,0 +1,40 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+
+	phuslog "github.com/phuslu/log"
+)
+
+var _presets sync.Map
+
+// RegisterPreset defines the field set name as keys, in order, so that
+// records of a common event type carry consistent fields:
+//
+//	log.RegisterPreset("httpreq", "method", "path", "status")
+//	log.Info().Func(log.WithPreset("httpreq", r.Method, r.URL.Path, 200)).Msg("served")
+func RegisterPreset(name string, keys ...string) {
+	_presets.Store(name, keys)
+}
+
+// WithPreset returns a field adding values under the keys of the preset
+// name. An unknown preset or a mismatched number of values is reported in
+// a "!BADPRESET" field instead.
+func WithPreset(name string, values ...any) func(e *phuslog.Entry) {
+	return func(e *phuslog.Entry) {
+		v, ok := _presets.Load(name)
+		if !ok {
+			e.Str("!BADPRESET", "unknown preset "+name)
+			return
+		}
+		keys := v.([]string)
+		if len(keys) != len(values) {
+			e.Str("!BADPRESET", fmt.Sprintf("preset %s takes %d values, got %d", name, len(keys), len(values)))
+			return
+		}
+		for i, key := range keys {
+			e.Any(key, values[i])
+		}
+	}
+}
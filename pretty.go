@@ -0,0 +1,32 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// prettyWriter re-encodes each record as indented JSON with sorted keys,
+// for reading deeply nested records during development.
+type prettyWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *prettyWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	p := e.Value()
+	var v map[string]any
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if d.Decode(&v) == nil {
+		if b, err := json.MarshalIndent(v, "", "  "); err == nil {
+			p = append(b, '\n')
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
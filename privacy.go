@@ -0,0 +1,76 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// A Classification is how sensitive a record is, for routing it only to
+// outputs cleared for it.
+type Classification int
+
+const (
+	Public Classification = iota + 1
+	Internal
+	Confidential
+)
+
+var classificationNames = [...]string{
+	Public:       "public",
+	Internal:     "internal",
+	Confidential: "confidential",
+}
+
+func (c Classification) String() string {
+	if c < Public || c > Confidential {
+		return ""
+	}
+	return classificationNames[c]
+}
+
+// Classified returns a field marking the record as classified c. Outputs
+// whose MaxClass is below c, and subscribers from SubscribeUpTo, do not
+// get it. A record with confidential
+// fields should be classified as confidential as a whole:
+//
+//	log.Info().Func(log.Classified(log.Confidential)).Str("ssn", ssn).Msg("verified")
+func Classified(c Classification) func(e *phuslog.Entry) {
+	return func(e *phuslog.Entry) {
+		e.Str(classificationKey, c.String())
+	}
+}
+
+const classificationKey = "classification"
+
+// classification returns the highest classification the top-level fields
+// of a record mark it with, or Public. A record that is not a JSON object
+// is taken as Confidential.
+func classification(record []byte) Classification {
+	d := json.NewDecoder(bytes.NewReader(record))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return Confidential
+	}
+	class := Public
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return Confidential
+		}
+		var raw json.RawMessage
+		if err := d.Decode(&raw); err != nil {
+			return Confidential
+		}
+		var s string
+		if t != classificationKey || json.Unmarshal(raw, &s) != nil {
+			continue
+		}
+		for c := Confidential; c > class; c-- {
+			if s == c.String() {
+				class = c
+			}
+		}
+	}
+	return class
+}
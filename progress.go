@@ -0,0 +1,79 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// ProgressInterval and ProgressStep throttle the records of a Progress:
+// one is logged once ProgressInterval has passed or ProgressStep percent
+// more of the total is done since the last one.
+var (
+	ProgressInterval = 10 * time.Second
+	ProgressStep     = 10.0
+)
+
+// ProgressLog reports the progress of a batch job.
+type ProgressLog struct {
+	name  string
+	total int64
+	start time.Time
+
+	mu      sync.Mutex
+	done    int64
+	last    time.Time
+	lastPct float64
+}
+
+// Progress starts reporting on the job name, which processes total items.
+// A total of zero means the size is unknown: records then carry the count
+// and rate without a percentage or ETA.
+func Progress(name string, total int64) *ProgressLog {
+	now := time.Now()
+	return &ProgressLog{name: name, total: total, start: now, last: now}
+}
+
+// Add records n more items done, logging the progress with its rate and
+// ETA when due, and always once a known total is reached.
+func (p *ProgressLog) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	before := p.done
+	p.done += n
+	known := p.total > 0
+	if known && before >= p.total {
+		return
+	}
+	now := time.Now()
+	pct := 100 * float64(p.done) / float64(max(p.total, 1))
+	due := now.Sub(p.last) >= ProgressInterval
+	if known {
+		due = due || p.done >= p.total || pct-p.lastPct >= ProgressStep
+	}
+	if !due {
+		return
+	}
+	p.last, p.lastPct = now, pct
+
+	elapsed := now.Sub(p.start)
+	rate := float64(p.done) / elapsed.Seconds()
+	e := bind(phuslog.InfoLevel).
+		Str("progress.name", p.name).
+		Int64("progress.done", p.done)
+	if known {
+		e.Int64("progress.total", p.total).
+			Float64("progress.percent", float64(int(pct*10))/10)
+	}
+	e.Float64("progress.rate", float64(int(rate*10))/10).
+		Dur("progress.elapsed", elapsed)
+	if known && p.done >= p.total {
+		e.Msg(p.name + " done")
+		return
+	}
+	if known && rate > 0 {
+		e.Dur("progress.eta", time.Duration(float64(p.total-p.done)/rate*float64(time.Second)))
+	}
+	e.Msg(p.name + " in progress")
+}
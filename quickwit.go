@@ -0,0 +1,96 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// QuickwitWriter ships records as NDJSON batches to the ingest API of a
+// Quickwit index:
+//
+//	qw := &log.QuickwitWriter{URL: "http://quickwit:7280", Index: "app-logs"}
+//	defer qw.Close()
+//	log.SetOutputs(
+//		log.Output{Writer: log.NewConsoleWriter(os.Stderr)},
+//		log.Output{Writer: qw, Level: phuslog.InfoLevel},
+//	)
+//
+// Records are posted from a background goroutine; Close, or the package's
+// Close, posts the last batch. Failed posts are retried a few times, then
+// reported through the self log.
+type QuickwitWriter struct {
+	// URL is the base URL of the Quickwit server.
+	URL string
+
+	// Index is the ID of the index ingested into.
+	Index string
+
+	// Commit is the commit mode of each request: "auto" (the default),
+	// "wait_for" or "force".
+	Commit string
+
+	// BatchSize is the most records posted at once, 1000 if zero.
+	BatchSize int
+
+	// FlushInterval is how long a record may wait for its batch to fill
+	// up, one second if zero.
+	FlushInterval time.Duration
+
+	// Header is added to every request, e.g. for a bearer token or the
+	// headers an authenticating proxy expects.
+	Header http.Header
+
+	// Client is the client posting the batches, one with a 10 second
+	// timeout if nil.
+	Client *http.Client
+
+	once sync.Once
+	s    *shipper
+}
+
+// WriteEntry implements phuslog.Writer.
+func (w *QuickwitWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	return w.Write(e.Value())
+}
+
+// Write queues p, a complete record.
+func (w *QuickwitWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		w.s = newShipper(w.send, w.BatchSize, w.FlushInterval)
+		addCloser(w)
+	})
+	if w.s == nil {
+		return 0, errShipperClosed
+	}
+	return w.s.write(p)
+}
+
+// Close posts the queued records and stops the writer.
+func (w *QuickwitWriter) Close() error {
+	w.once.Do(func() {})
+	if w.s == nil {
+		return nil
+	}
+	return w.s.close()
+}
+
+func (w *QuickwitWriter) send(records [][]byte) error {
+	u := w.URL + "/api/v1/" + url.PathEscape(w.Index) + "/ingest"
+	if w.Commit != "" {
+		u += "?commit=" + url.QueryEscape(w.Commit)
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(bytes.Join(records, nil)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range w.Header {
+		req.Header[k] = v
+	}
+	return post(w.Client, req)
+}
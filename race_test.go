@@ -0,0 +1,6 @@
+//go:build race
+
+package log
+
+// raceEnabled is set when testing with -race, which inflates allocations.
+const raceEnabled = true
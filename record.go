@@ -0,0 +1,28 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Rewrite returns a copy of r whose attrs are passed through f, dropping
+// those for which f reports false. r itself is left untouched.
+func Rewrite(r slog.Record, f func(a slog.Attr) (slog.Attr, bool)) slog.Record {
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		if a, ok := f(a); ok {
+			nr.AddAttrs(a)
+		}
+		return true
+	})
+	return nr
+}
+
+// Reemit dispatches a copy of r to h, if h is enabled for its level, so
+// routing handlers can hand records on without sharing them.
+func Reemit(ctx context.Context, h slog.Handler, r slog.Record) error {
+	if !h.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handle(ctx, r.Clone())
+}
@@ -0,0 +1,110 @@
+package log
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// _resource holds the fields added to every record, built by
+// buildResource.
+var _resource atomic.Pointer[phuslog.Context]
+
+var (
+	_resourceMu    sync.Mutex
+	_resourceAttrs []slog.Attr
+	_appName       string
+	_hostname      string
+)
+
+// SetResource sets static fields describing the deployment, added to every
+// record after the level, whether logged through the package, slog or a
+// handler from NewConsoleHandler created afterwards. Call it once at
+// startup:
+//
+//	log.SetResource(
+//		slog.String("service.name", "billing"),
+//		slog.String("service.version", version),
+//		slog.String("deployment.environment", "prod"),
+//	)
+//
+// Groups are flattened into dotted keys.
+func SetResource(attrs ...slog.Attr) {
+	_resourceMu.Lock()
+	defer _resourceMu.Unlock()
+	_resourceAttrs = attrs
+	buildResource()
+}
+
+// SetAppName adds an app field with name to every record, like the
+// resource fields. "" removes it.
+func SetAppName(name string) {
+	_resourceMu.Lock()
+	defer _resourceMu.Unlock()
+	_appName = name
+	buildResource()
+}
+
+// SetHostname adds a host field with name to every record, like the
+// resource fields. "" removes it.
+func SetHostname(name string) {
+	_resourceMu.Lock()
+	defer _resourceMu.Unlock()
+	_hostname = name
+	buildResource()
+}
+
+// appName returns the name set by SetAppName.
+func appName() string {
+	_resourceMu.Lock()
+	defer _resourceMu.Unlock()
+	return _appName
+}
+
+// DetectHostname sets the host field to the name reported by the kernel.
+// Call it again where the hostname can change, as with DHCP.
+func DetectHostname() error {
+	name, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	SetHostname(name)
+	return nil
+}
+
+// buildResource rebuilds _resource. _resourceMu must be held.
+func buildResource() {
+	e := phuslog.NewContext(nil)
+	if _appName != "" {
+		e.Str("app", _appName)
+	}
+	if _hostname != "" {
+		e.Str("host", _hostname)
+	}
+	addAttrs(e, "", _resourceAttrs)
+	c := e.Value()
+	_resource.Store(&c)
+	resetSlog()
+}
+
+func addAttrs(e *phuslog.Entry, prefix string, attrs []slog.Attr) {
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			addAttrs(e, prefix+a.Key+".", v.Group())
+			continue
+		}
+		e.Any(prefix+a.Key, v.Any())
+	}
+}
+
+// resource returns the fields set by SetResource.
+func resource() phuslog.Context {
+	if c := _resource.Load(); c != nil {
+		return *c
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"context"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// RetryNoticeAfter is the attempt from which Retry logs at notice level
+// instead of info.
+var RetryNoticeAfter = 3
+
+// Retry logs attempt of the retry loop name, which failed with err and will
+// be retried after nextDelay. totalDelay is the time the loop has waited so
+// far, which only the caller knows; it is logged with the attempt.
+//
+// Call it with a nil err once the operation succeeds, or with a negative
+// nextDelay when giving up, which is logged at error level.
+func Retry(ctx context.Context, name string, attempt int, err error, nextDelay, totalDelay time.Duration) {
+	var e *phuslog.Entry
+	switch {
+	case err == nil:
+		e = bind(phuslog.InfoLevel)
+	case nextDelay < 0:
+		e = bind(phuslog.ErrorLevel).Caller(2)
+	default:
+		level := phuslog.InfoLevel
+		if attempt >= RetryNoticeAfter {
+			level = phuslog.WarnLevel
+		}
+		e = bind(level).Dur("retry.next_delay", nextDelay)
+	}
+	e = e.Context(Ctx(ctx)).Str("retry.name", name).Int("retry.attempt", attempt).Dur("retry.total_delay", totalDelay)
+
+	switch {
+	case err == nil:
+		e.Msg(name + " succeeded")
+	case nextDelay < 0:
+		e.Err(err).Msg(name + " gave up")
+	default:
+		e.Err(err).Msg(name + " failed, retrying")
+	}
+}
@@ -0,0 +1,185 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// recordRewrite renames the core keys of the records written to an output
+// and drops their empty values.
+type recordRewrite struct {
+	names FieldNames
+	omit  Omit
+
+	// keep is r without the renames, for outputs reading the default keys
+	// back, or nil if that changes nothing.
+	keep *recordRewrite
+}
+
+var (
+	// _rewrite is the rewrite set by SetFieldNames and SetOmit for every
+	// output, or nil.
+	_rewrite atomic.Pointer[recordRewrite]
+
+	_rewriteMu  sync.Mutex
+	_fieldNames FieldNames
+	_omit       Omit
+)
+
+// setRewrite updates the rewrite for every output with f.
+func setRewrite(f func()) {
+	lazyInit()
+	_rewriteMu.Lock()
+	defer _rewriteMu.Unlock()
+	f()
+	_rewrite.Store(newRewrite(_fieldNames, _omit))
+}
+
+// newRewrite returns the rewrite for names and omit, or nil if it changes
+// nothing.
+func newRewrite(names FieldNames, omit Omit) *recordRewrite {
+	r := &recordRewrite{names: names, omit: omit}
+	if omit != 0 {
+		r.keep = &recordRewrite{omit: omit}
+		r.keep.keep = r.keep
+	}
+	if r.name(phuslog.TimeKey) == "" && r.name(phuslog.LevelKey) == "" &&
+		r.name(phuslog.MessageKey) == "" && r.name(phuslog.CallerKey) == "" {
+		return r.keep
+	}
+	return r
+}
+
+// name returns the key replacing key, or "" if it is kept.
+func (r *recordRewrite) name(key string) string {
+	var name string
+	switch key {
+	case phuslog.TimeKey:
+		name = r.names.Time
+	case phuslog.LevelKey:
+		name = r.names.Level
+	case phuslog.MessageKey:
+		name = r.names.Message
+	case phuslog.CallerKey:
+		name = r.names.Caller
+	}
+	if name == key {
+		return ""
+	}
+	return name
+}
+
+// apply returns e rewritten, or e itself if it is not a JSON object.
+func (r *recordRewrite) apply(e *phuslog.Entry) *phuslog.Entry {
+	b, ok := r.object(e.Value(), true)
+	if !ok {
+		return e
+	}
+	n := phuslog.NewContext(append(b, '\n'))
+	n.Level = e.Level
+	return n
+}
+
+// object re-encodes the JSON object p with its empty values dropped and,
+// at the top, its keys renamed, reporting whether p was one.
+func (r *recordRewrite) object(p []byte, top bool) ([]byte, bool) {
+	d := json.NewDecoder(bytes.NewReader(p))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return nil, false
+	}
+	b := make([]byte, 0, len(p)+16)
+	b = append(b, '{')
+	prev := d.InputOffset()
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, false
+		}
+		key := bytes.TrimLeft(p[prev:d.InputOffset()], ", \t\r\n")
+		var value json.RawMessage
+		if err := d.Decode(&value); err != nil {
+			return nil, false
+		}
+		prev = d.InputOffset()
+		if top && core(t.(string)) {
+			if name := r.name(t.(string)); name != "" {
+				key, _ = json.Marshal(name)
+			}
+		} else if r.omit != 0 {
+			var kept bool
+			if value, kept = r.value(value); !kept {
+				continue
+			}
+		}
+		if len(b) > 1 {
+			b = append(b, ',')
+		}
+		b = append(b, key...)
+		b = append(b, ':')
+		b = append(b, value...)
+	}
+	return append(b, '}'), true
+}
+
+// value returns v with empty values dropped from it, reporting whether it
+// is kept itself.
+func (r *recordRewrite) value(v []byte) ([]byte, bool) {
+	switch {
+	case v[0] == '"':
+		return v, r.omit&OmitEmptyString == 0 || len(v) != 2
+	case v[0] == 'n':
+		return v, r.omit&OmitNil == 0
+	case v[0] == '-' || '0' <= v[0] && v[0] <= '9':
+		f, err := strconv.ParseFloat(string(v), 64)
+		return v, r.omit&OmitZeroNumber == 0 || err != nil || f != 0
+	case v[0] == '{':
+		o, ok := r.object(v, false)
+		if !ok {
+			return v, true
+		}
+		return o, r.omit&OmitEmptyGroup == 0 || len(o) != 2
+	}
+	return v, true
+}
+
+// core reports whether key is one every record carries, which is never
+// dropped.
+func core(key string) bool {
+	switch key {
+	case phuslog.TimeKey, phuslog.LevelKey, phuslog.MessageKey, phuslog.CallerKey, phuslog.CallerFuncKey:
+		return true
+	}
+	return false
+}
+
+// writeEntry writes e to w rewritten by r, keeping the default keys if w
+// reads them back, as the console and the journal do.
+func writeEntry(w phuslog.Writer, e *phuslog.Entry, r *recordRewrite) (n int, err error) {
+	switch w := w.(type) {
+	case *phuslog.ConsoleWriter, *JournalWriter:
+		if r != nil && r.keep != nil {
+			e = r.keep.apply(e)
+		}
+		return w.WriteEntry(e)
+	case *phuslog.MultiEntryWriter:
+		for _, m := range *w {
+			if n1, err1 := writeEntry(m, e, r); err1 != nil {
+				err = err1
+			} else {
+				n = n1
+			}
+		}
+		return
+	case levelOutputs:
+		return w.write(e, r)
+	}
+	if r != nil {
+		e = r.apply(e)
+	}
+	return w.WriteEntry(e)
+}
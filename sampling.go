@@ -0,0 +1,92 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// maxKeepEvery bounds how far adaptive sampling thins records.
+const maxKeepEvery = 1024
+
+var (
+	_samplingMu   sync.Mutex
+	_samplingStop chan struct{}
+
+	_sampleIncoming atomic.Int64
+	_sampleDropped  atomic.Int64
+	_sampleKeep     atomic.Int64
+)
+
+// SetAdaptiveSampling thins debug and info records while more than
+// threshold records per second are logged, halving the share kept each
+// second the rate stays above it and doubling it back once the rate falls
+// below half of it. Notice and more severe records are always kept. A
+// notice record reports each change of the effective rate, and every
+// minute while sampling the number of records dropped. A threshold of 0
+// turns sampling off.
+func SetAdaptiveSampling(threshold int) {
+	_samplingMu.Lock()
+	defer _samplingMu.Unlock()
+	if _samplingStop != nil {
+		close(_samplingStop)
+		_samplingStop = nil
+	}
+	_sampleKeep.Store(1)
+	if threshold <= 0 {
+		return
+	}
+	_samplingStop = make(chan struct{})
+	go adaptSampling(int64(threshold), _samplingStop)
+}
+
+func adaptSampling(threshold int64, stop chan struct{}) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	var ticks int
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		rate := _sampleIncoming.Swap(0)
+		keep := _sampleKeep.Load()
+		switch {
+		case rate > threshold && keep < maxKeepEvery:
+			keep *= 2
+		case rate < threshold/2 && keep > 1:
+			keep /= 2
+		default:
+			if ticks++; keep > 1 && ticks%60 == 0 {
+				reportSampling("records sampled", rate, keep)
+			}
+			continue
+		}
+		_sampleKeep.Store(keep)
+		reportSampling("sampling rate changed", rate, keep)
+	}
+}
+
+func reportSampling(msg string, rate, keep int64) {
+	bind(phuslog.WarnLevel).
+		Int64("sample.rate", rate).
+		Int64("sample.keep_every", keep).
+		Int64("sample.dropped", _sampleDropped.Swap(0)).
+		Msg(msg)
+}
+
+// sampled reports whether e is dropped by adaptive sampling.
+func sampled(e *phuslog.Entry) bool {
+	n := _sampleIncoming.Add(1)
+	if e.Level >= phuslog.WarnLevel {
+		return false
+	}
+	if keep := _sampleKeep.Load(); keep > 1 && n%keep != 0 {
+		_sampleDropped.Add(1)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// scopeKey is the field carrying the scope path of a record.
+const scopeKey = "log.scope"
+
+// _scopes holds the scope path of each goroutine, keyed by goroutine id.
+var _scopes sync.Map
+
+// _scoping is set once Scope is first used.
+var _scoping atomic.Bool
+
+// Scope logs name at info level and opens a scope on the current
+// goroutine: until end is called, the records it logs with the leveled
+// helpers carry a log.scope field and are indented on the console under
+// name.
+// Scopes nest, their names joined with "/".
+//
+//	defer log.Scope("loading plugins")()
+func Scope(name string) (end func()) {
+	Info().Msg(name)
+	_scoping.Store(true)
+	goid := phuslog.Goid()
+	prev, ok := _scopes.Load(goid)
+	path := name
+	if ok {
+		path = prev.(string) + "/" + name
+	}
+	_scopes.Store(goid, path)
+	return func() {
+		if ok {
+			_scopes.Store(goid, prev)
+		} else {
+			_scopes.Delete(goid)
+		}
+	}
+}
+
+// scope returns the scope path of the current goroutine, or "".
+func scope() string {
+	if !_scoping.Load() {
+		return ""
+	}
+	s, _ := _scopes.Load(phuslog.Goid())
+	path, _ := s.(string)
+	return path
+}
@@ -0,0 +1,37 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	phuslog "github.com/phuslu/log"
+)
+
+var (
+	_selfMu sync.Mutex
+	_self   = phuslog.Logger{
+		Level:      phuslog.WarnLevel,
+		TimeFormat: phuslog.TimeFormatUnixMs,
+		Writer:     phuslog.IOWriter{Writer: os.Stderr},
+	}
+)
+
+// SetSelfLog sets where the package reports its own problems, such as
+// failed archive compression or systemd notification, and the minimum
+// level reported. These records bypass the configured outputs, so a
+// failing output cannot feed back into itself. They go to stderr from
+// notice level by default.
+func SetSelfLog(w io.Writer, level phuslog.Level) {
+	_selfMu.Lock()
+	defer _selfMu.Unlock()
+	_self.Writer = phuslog.IOWriter{Writer: w}
+	_self.SetLevel(level)
+}
+
+// selfLog reports an internal problem at level.
+func selfLog(level phuslog.Level, msg string, err error) {
+	_selfMu.Lock()
+	defer _selfMu.Unlock()
+	_self.WithLevel(level).Str("logger", "self").Err(err).Msg(msg)
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package log
+
+import (
+	phuslog "github.com/phuslu/log"
+)
+
+// serviceWriter returns nil: only Windows services are detected.
+func serviceWriter() phuslog.Writer {
+	return nil
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	phuslog "github.com/phuslu/log"
+)
+
+var procGetConsoleWindow = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleWindow")
+
+// serviceWriter returns the Windows Event Log writer when the process runs
+// without a console, as Windows services do, so that console output is
+// not lost.
+func serviceWriter() phuslog.Writer {
+	if hwnd, _, _ := procGetConsoleWindow.Call(); hwnd != 0 {
+		return nil
+	}
+	if phuslog.IsTerminal(os.Stderr.Fd()) {
+		return nil
+	}
+	return &eventlogWriter{}
+}
+
+// eventlogWriter writes to the Windows Event Log with the name set by
+// SetAppName as the event source, or else the program name. The source is
+// chosen on the first write, so SetAppName may be called after setup.
+type eventlogWriter struct {
+	once sync.Once
+	w    *phuslog.EventlogWriter
+}
+
+func (w *eventlogWriter) WriteEntry(e *phuslog.Entry) (int, error) {
+	w.once.Do(func() {
+		source := appName()
+		if source == "" {
+			source = strings.TrimSuffix(filepath.Base(os.Args[0]), filepath.Ext(os.Args[0]))
+		}
+		w.w = &phuslog.EventlogWriter{Source: source, ID: 1}
+	})
+	return w.w.WriteEntry(e)
+}
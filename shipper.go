@@ -0,0 +1,164 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+const (
+	// shipQueue is how many batches may wait to be posted before new ones
+	// are dropped.
+	shipQueue = 64
+	// shipRetries is how often a failed post is retried.
+	shipRetries = 3
+)
+
+// shipBackoff is the delay before the first retry, doubled for each one,
+// and shipCloseTimeout the longest close waits for the queue to drain.
+// They are shortened in tests.
+var (
+	shipBackoff      = 500 * time.Millisecond
+	shipCloseTimeout = 10 * time.Second
+)
+
+// shipClient posts the batches of writers without a Client of their own.
+var shipClient = &http.Client{Timeout: 10 * time.Second}
+
+var (
+	errShipperClosed  = errors.New("log: writer closed")
+	errShipperTimeout = errors.New("log: timed out posting queued records")
+)
+
+// shipper collects records into batches posted by send from a single
+// goroutine, in order, for the writers shipping to log stores over HTTP.
+// Batches are posted once they hold batchSize records or interval after
+// their first record. Failed posts are retried, then dropped and reported
+// through the self log, as are batches that find the queue full.
+type shipper struct {
+	send      func(records [][]byte) error
+	batchSize int
+	interval  time.Duration
+
+	mu     sync.Mutex
+	batch  [][]byte
+	timer  *time.Timer
+	queue  chan [][]byte
+	done   chan struct{}
+	closed bool
+}
+
+func newShipper(send func(records [][]byte) error, batchSize int, interval time.Duration) *shipper {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	s := &shipper{
+		send:      send,
+		batchSize: batchSize,
+		interval:  interval,
+		queue:     make(chan [][]byte, shipQueue),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *shipper) write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, errShipperClosed
+	}
+	s.batch = append(s.batch, bytes.Clone(p))
+	if len(s.batch) >= s.batchSize {
+		s.flush()
+	} else if s.timer == nil {
+		s.timer = time.AfterFunc(s.interval, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.flush()
+		})
+	}
+	return len(p), nil
+}
+
+// flush queues the current batch. s.mu must be held.
+func (s *shipper) flush() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.batch) == 0 || s.closed {
+		return
+	}
+	select {
+	case s.queue <- s.batch:
+	default:
+		selfLog(phuslog.ErrorLevel, "log shipping queue full, batch dropped", fmt.Errorf("%d records", len(s.batch)))
+	}
+	s.batch = nil
+}
+
+func (s *shipper) run() {
+	defer close(s.done)
+	for batch := range s.queue {
+		for attempt := 0; ; attempt++ {
+			err := s.send(batch)
+			if err == nil {
+				break
+			}
+			if attempt == shipRetries {
+				selfLog(phuslog.ErrorLevel, "log shipping failed, batch dropped", fmt.Errorf("%d records: %w", len(batch), err))
+				break
+			}
+			time.Sleep(shipBackoff << attempt)
+		}
+	}
+}
+
+// close posts the pending records and waits for the queue to drain, for
+// at most shipCloseTimeout.
+func (s *shipper) close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.flush()
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+	select {
+	case <-s.done:
+		return nil
+	case <-time.After(shipCloseTimeout):
+		return errShipperTimeout
+	}
+}
+
+// post sends req with client, treating responses other than 2xx as errors.
+func post(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = shipClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
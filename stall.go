@@ -0,0 +1,58 @@
+package log
+
+import (
+	"runtime"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// WatchStalls logs a self-diagnostic record at notice level when nothing
+// has been logged for period, which in a process that logs steadily hints
+// at wedged workers. The record carries the goroutine count and, if stacks
+// is set, the stacks of all goroutines. It is logged once per silence.
+// The returned function stops the watch.
+func WatchStalls(period time.Duration, stacks bool) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(period)
+		defer t.Stop()
+		last, reported := _records.Load(), false
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			n := _records.Load()
+			if n != last {
+				last, reported = n, false
+				continue
+			}
+			if reported {
+				continue
+			}
+			e := bind(phuslog.WarnLevel).
+				Int("goroutines", runtime.NumGoroutine()).
+				Dur("silence", period)
+			if stacks {
+				e.Bytes("stacks", allStacks())
+			}
+			e.Msg("no records logged within period")
+			last, reported = _records.Load(), true
+		}
+	}()
+	return func() { close(done) }
+}
+
+// allStacks returns the stacks of all goroutines.
+func allStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
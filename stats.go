@@ -0,0 +1,44 @@
+package log
+
+import (
+	"runtime/metrics"
+	"sync/atomic"
+)
+
+// statsSampleEvery is how often a record's write is measured for Stats.
+const statsSampleEvery = 1024
+
+var (
+	_sampledWrites atomic.Int64
+	_sampledAllocs atomic.Int64
+)
+
+// Stats are runtime counters of the records written by the package.
+type Stats struct {
+	// Records is the number of records written.
+	Records int64
+	// AllocsPerRecord is the mean number of heap objects allocated while
+	// writing a record, sampled every 1024 records. Allocations made by
+	// other goroutines at the same time are counted too, so it is an
+	// upper bound.
+	AllocsPerRecord float64
+}
+
+// ReadStats returns the current counters.
+func ReadStats() Stats {
+	s := Stats{Records: _records.Load()}
+	if n := _sampledWrites.Load(); n > 0 {
+		s.AllocsPerRecord = float64(_sampledAllocs.Load()) / float64(n)
+	}
+	return s
+}
+
+// heapAllocs returns the number of heap objects allocated so far.
+func heapAllocs() int64 {
+	s := [1]metrics.Sample{{Name: "/gc/heap/allocs:objects"}}
+	metrics.Read(s[:])
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return int64(s[0].Value.Uint64())
+}
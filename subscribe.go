@@ -0,0 +1,144 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// Record is a record as given to subscribers. The same Record goes to
+// every subscriber, so it must not be modified.
+type Record struct {
+	Time    time.Time
+	Level   phuslog.Level
+	Message string
+
+	// Fields holds the other top-level fields, decoded by encoding/json
+	// with numbers as json.Number.
+	Fields map[string]any
+
+	// JSON is the record as written, without the trailing newline.
+	JSON []byte
+}
+
+// decodeRecord decodes the JSON record p.
+func decodeRecord(p []byte) Record {
+	r := Record{JSON: bytes.TrimSuffix(slices.Clone(p), []byte("\n"))}
+	d := json.NewDecoder(bytes.NewReader(r.JSON))
+	d.UseNumber()
+	if d.Decode(&r.Fields) != nil {
+		return r
+	}
+	if s, ok := r.Fields[phuslog.LevelKey].(string); ok {
+		r.Level = journalLevels[s].level
+		delete(r.Fields, phuslog.LevelKey)
+	}
+	if s, ok := r.Fields[phuslog.MessageKey].(string); ok {
+		r.Message = s
+		delete(r.Fields, phuslog.MessageKey)
+	}
+	if t, ok := recordTime(r.Fields[phuslog.TimeKey]); ok {
+		r.Time = t
+		delete(r.Fields, phuslog.TimeKey)
+	}
+	return r
+}
+
+// recordTime decodes the time field v, as encoded with the time format of
+// the package.
+func recordTime(v any) (time.Time, bool) {
+	format := _default.TimeFormat
+	switch v := v.(type) {
+	case json.Number:
+		if strings.Contains(string(v), ".") {
+			f, err := v.Float64()
+			return time.UnixMilli(int64(f * 1000)), err == nil
+		}
+		n, err := v.Int64()
+		if format == phuslog.TimeFormatUnix {
+			return time.Unix(n, 0), err == nil
+		}
+		return time.UnixMilli(n), err == nil
+	case string:
+		t, err := time.Parse(format, v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// subscriber is a channel given to Subscribe, with the most sensitive
+// classification of records it gets, zero for all.
+type subscriber struct {
+	ch       chan<- Record
+	maxClass Classification
+}
+
+var (
+	_subscribersMu sync.Mutex
+	_subscribers   atomic.Pointer[[]subscriber]
+)
+
+// Subscribe sends every record, decoded, to ch until the returned function
+// is called, for in-process consumers such as a TUI. Records are dropped
+// for a subscriber whose channel is full, so a slow consumer never blocks
+// logging.
+func Subscribe(ch chan<- Record) (unsubscribe func()) {
+	return SubscribeUpTo(ch, 0)
+}
+
+// SubscribeUpTo is like Subscribe, but only sends the records classified
+// at most maxClass, as Output.MaxClass does for outputs.
+func SubscribeUpTo(ch chan<- Record, maxClass Classification) (unsubscribe func()) {
+	_subscribersMu.Lock()
+	defer _subscribersMu.Unlock()
+	var subs []subscriber
+	if p := _subscribers.Load(); p != nil {
+		subs = slices.Clone(*p)
+	}
+	subs = append(subs, subscriber{ch, maxClass})
+	_subscribers.Store(&subs)
+
+	return func() {
+		_subscribersMu.Lock()
+		defer _subscribersMu.Unlock()
+		subs := slices.Clone(*_subscribers.Load())
+		if i := slices.IndexFunc(subs, func(s subscriber) bool { return s.ch == ch }); i >= 0 {
+			subs = slices.Delete(subs, i, i+1)
+		}
+		_subscribers.Store(&subs)
+	}
+}
+
+// publish sends record to the subscribers cleared for it.
+func publish(record []byte) {
+	p := _subscribers.Load()
+	if p == nil || len(*p) == 0 {
+		return
+	}
+	var class Classification
+	var r *Record
+	for _, s := range *p {
+		if s.maxClass != 0 {
+			if class == 0 {
+				class = classification(record)
+			}
+			if class > s.maxClass {
+				continue
+			}
+		}
+		if r == nil {
+			d := decodeRecord(record)
+			r = &d
+		}
+		select {
+		case s.ch <- *r:
+		default:
+		}
+	}
+}
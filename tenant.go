@@ -0,0 +1,44 @@
+package log
+
+import (
+	"context"
+	"sync/atomic"
+
+	phuslog "github.com/phuslu/log"
+)
+
+var tenantResolver atomic.Pointer[func(ctx context.Context) string]
+
+// SetTenantResolver sets f to resolve the tenant of contexts that were not
+// given one with WithTenant. Ctx tags its fields with the resolved "tenant"
+// unless f returns "". A nil f removes the resolver.
+func SetTenantResolver(f func(ctx context.Context) string) {
+	if f == nil {
+		tenantResolver.Store(nil)
+		return
+	}
+	tenantResolver.Store(&f)
+}
+
+func resolveTenant(ctx context.Context) string {
+	if f := tenantResolver.Load(); f != nil {
+		return (*f)(ctx)
+	}
+	return ""
+}
+
+// WithTenant returns a copy of ctx belonging to tenant, whose fields carry
+// it under "tenant".
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	ctx = context.WithValue(ctx, tenantKey, tenant)
+	return withFields(ctx, func(e *phuslog.Entry) { e.Str("tenant", tenant) })
+}
+
+// Tenant returns the tenant of ctx set by WithTenant, falling back to the
+// resolver set by SetTenantResolver.
+func Tenant(ctx context.Context) string {
+	if t, ok := ctx.Value(tenantKey).(string); ok {
+		return t
+	}
+	return resolveTenant(ctx)
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// timerReservoir bounds the durations a Timer keeps per interval.
+const timerReservoir = 4096
+
+// Timer collects the durations of a repeated operation and logs a summary
+// of them with count, p50, p95 and max every interval, giving latency
+// insight where no metrics system is available.
+type Timer struct {
+	name     string
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu      sync.Mutex
+	count   int
+	max     time.Duration
+	samples []time.Duration
+}
+
+// NewTimer starts a Timer for the operation name. Call Stop to log the
+// last summary and release it.
+func NewTimer(name string, interval time.Duration) *Timer {
+	t := &Timer{name: name, stop: make(chan struct{})}
+	go func() {
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-tick.C:
+				t.flush()
+			}
+		}
+	}()
+	return t
+}
+
+// Observe records one duration of the operation.
+func (t *Timer) Observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count++
+	t.max = max(t.max, d)
+	if len(t.samples) < timerReservoir {
+		t.samples = append(t.samples, d)
+	} else if i := rand.IntN(t.count); i < timerReservoir {
+		t.samples[i] = d
+	}
+}
+
+// Since records the time elapsed since start:
+//
+//	defer timer.Since(time.Now())
+func (t *Timer) Since(start time.Time) {
+	t.Observe(time.Since(start))
+}
+
+// Stop logs the summary of the durations observed since the last one and
+// stops the Timer. Later calls do nothing.
+func (t *Timer) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+		t.flush()
+	})
+}
+
+// flush logs and resets the summary of the current interval.
+func (t *Timer) flush() {
+	t.mu.Lock()
+	count, maxd, samples := t.count, t.max, t.samples
+	t.count, t.max, t.samples = 0, 0, nil
+	t.mu.Unlock()
+	if count == 0 {
+		return
+	}
+	slices.Sort(samples)
+	bind(phuslog.InfoLevel).
+		Str("timer.name", t.name).
+		Int("timer.count", count).
+		Dur("timer.p50", percentile(samples, 50)).
+		Dur("timer.p95", percentile(samples, 95)).
+		Dur("timer.max", maxd).
+		Msg(t.name + " timings")
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted: the
+// smallest sample at least p percent of them do not exceed.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	return sorted[max((len(sorted)*p+99)/100-1, 0)]
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+type trigger struct {
+	key, value string
+	// str and num are the field as bound with a string or numeric value.
+	str, num []byte
+}
+
+var (
+	_triggersMu sync.Mutex
+	// _triggers is replaced, never modified, so it is read without locking.
+	_triggers atomic.Pointer[[]trigger]
+)
+
+// Trigger writes the records logged from goroutines whose bound fields
+// have key set to value at every level, whatever the level set, to debug a
+// single customer without raising verbosity for all:
+//
+//	log.Trigger("user_id", "12345")
+//
+// Only fields bound with Bind are matched, not those added to a record.
+func Trigger(key, value string) {
+	_triggersMu.Lock()
+	defer _triggersMu.Unlock()
+	t := trigger{
+		key:   key,
+		value: value,
+		str:   []byte(`"` + key + `":` + strconv.Quote(value)),
+		num:   []byte(`"` + key + `":` + value),
+	}
+	var ts []trigger
+	if p := _triggers.Load(); p != nil {
+		ts = slices.Clone(*p)
+	}
+	ts = append(ts, t)
+	_triggers.Store(&ts)
+}
+
+// Untrigger removes the trigger for key and value.
+func Untrigger(key, value string) {
+	_triggersMu.Lock()
+	defer _triggersMu.Unlock()
+	p := _triggers.Load()
+	if p == nil {
+		return
+	}
+	ts := slices.DeleteFunc(slices.Clone(*p), func(t trigger) bool {
+		return t.key == key && t.value == value
+	})
+	if len(ts) == 0 {
+		_triggers.Store(nil)
+		return
+	}
+	_triggers.Store(&ts)
+}
+
+// triggered reports whether the fields bound to the current goroutine
+// match a trigger.
+func triggered() bool {
+	p := _triggers.Load()
+	if p == nil {
+		return false
+	}
+	c := bound()
+	if len(c) == 0 {
+		return false
+	}
+	for _, t := range *p {
+		for _, f := range [][]byte{t.str, t.num} {
+			// The field must end where the value does.
+			if i := bytes.Index(c, f); i >= 0 && (i+len(f) == len(c) || c[i+len(f)] == ',') {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TriggerHandler returns a handler for a control endpoint managing
+// triggers: GET lists them as key=value lines, POST adds and DELETE
+// removes the one given by the key and value query parameters.
+func TriggerHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, value := r.URL.Query().Get("key"), r.URL.Query().Get("value")
+		switch r.Method {
+		case http.MethodGet:
+			if p := _triggers.Load(); p != nil {
+				for _, t := range *p {
+					fmt.Fprintf(w, "%s=%s\n", t.key, t.value)
+				}
+			}
+			return
+		case http.MethodPost, http.MethodDelete:
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if key == "" || value == "" {
+			http.Error(w, "key and value are required", http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodPost {
+			Trigger(key, value)
+		} else {
+			Untrigger(key, value)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"context"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// Watchdog logs at notice level if the operation name is still running
+// after threshold, and at info level with its duration once the returned
+// function is called:
+//
+//	defer log.Watchdog(ctx, "load users", 2*time.Second)()
+func Watchdog(ctx context.Context, name string, threshold time.Duration) func() {
+	start := time.Now()
+	t := time.AfterFunc(threshold, func() {
+		bind(phuslog.WarnLevel).Context(Ctx(ctx)).
+			Str("op", name).
+			Dur("op.elapsed", time.Since(start)).
+			Msg(name + " is taking longer than " + threshold.String())
+	})
+	return func() {
+		slow := !t.Stop()
+		bind(phuslog.InfoLevel).Context(Ctx(ctx)).
+			Str("op", name).
+			Dur("op.duration", time.Since(start)).
+			Bool("op.slow", slow).
+			Msg(name + " finished")
+	}
+}
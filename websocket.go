@@ -0,0 +1,65 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	phuslog "github.com/phuslu/log"
+)
+
+// WSConn tracks one WebSocket connection for lifecycle records.
+type WSConn struct {
+	ctx      context.Context
+	start    time.Time
+	received atomic.Int64
+	sent     atomic.Int64
+}
+
+// WebSocket logs the upgrade of r and returns the tracker of its
+// connection. Its records carry ws.conn_id, the request path and the remote
+// address, along with the fields of r's context.
+func WebSocket(r *http.Request) *WSConn {
+	ctx := withFields(r.Context(), func(e *phuslog.Entry) {
+		e.Str("ws.conn_id", phuslog.NewXID().String()).
+			Str("http.path", r.URL.Path).
+			Str("http.remote", r.RemoteAddr)
+	})
+	bind(phuslog.InfoLevel).Context(Ctx(ctx)).Msg("websocket upgraded")
+	return &WSConn{ctx: ctx, start: time.Now()}
+}
+
+// Context returns a context carrying the connection's fields, for records
+// logged about the connection with Ctx.
+func (c *WSConn) Context() context.Context {
+	return c.ctx
+}
+
+// Received counts a message read from the connection.
+func (c *WSConn) Received() {
+	c.received.Add(1)
+}
+
+// Sent counts a message written to the connection.
+func (c *WSConn) Sent() {
+	c.sent.Add(1)
+}
+
+// Closed logs the end of the connection with its close code, message
+// counts and duration. A non-nil err is logged at error level.
+func (c *WSConn) Closed(code int, err error) {
+	level := phuslog.InfoLevel
+	if err != nil {
+		level = phuslog.ErrorLevel
+	}
+	e := bind(level).Context(Ctx(c.ctx)).
+		Int("ws.close_code", code).
+		Int64("ws.received", c.received.Load()).
+		Int64("ws.sent", c.sent.Load()).
+		Dur("ws.duration", time.Since(c.start))
+	if err != nil {
+		e.Err(err)
+	}
+	e.Msg("websocket closed")
+}